@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/backup"
+	"github.com/walkccc/greenlight/migrations"
+)
+
+// backupTimeout bounds how long a backup or a restore may take.
+const backupTimeout = time.Hour
+
+// runCommand runs the subcommand named by the first of args instead of the
+// API server:
+//
+//	api -db-dsn=... backup greenlight.tar.gz
+//	api -db-dsn=... restore greenlight.tar.gz
+func runCommand(db *sql.DB, logger *slog.Logger, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: api [flags] backup|restore <file>")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+	defer cancel()
+
+	switch args[0] {
+	case "backup":
+		return runBackup(ctx, db, logger, args[1])
+	case "restore":
+		return runRestore(ctx, db, logger, args[1])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runBackup writes an archive of the database to path. The archive is written
+// to a temporary file first, so that a failed backup never leaves a truncated
+// archive behind, nor overwrites a previous one.
+func runBackup(ctx context.Context, db *sql.DB, logger *slog.Logger, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".greenlight-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	manifest, err := backup.Create(ctx, db, f, version)
+	if err != nil {
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(f.Name(), path)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Backup created.", "file", path, "schema_version", manifest.SchemaVersion, "tables", len(manifest.Tables))
+	return nil
+}
+
+// runRestore loads the archive at path into the database, which must be empty.
+// The database must already have the extensions that the migrations rely on,
+// such as citext.
+func runRestore(ctx context.Context, db *sql.DB, logger *slog.Logger, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	manifest, err := backup.Restore(ctx, db, f, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(
+		"Backup restored.",
+		"file", path,
+		"schema_version", manifest.SchemaVersion,
+		"app_version", manifest.AppVersion,
+		"created_at", manifest.CreatedAt,
+	)
+	return nil
+}
@@ -9,7 +9,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey          = contextKey("user")
+	runtimeFormatContextKey = contextKey("runtime_format")
+)
 
 // contextSetUser returns a new copy of the request with the provided User
 // struct added to the context. Note that we use our userContextKey constant as
@@ -27,3 +30,28 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// contextSetRuntimeFormat returns a new copy of the request with the runtime
+// format requested by the client added to the context.
+func (app *application) contextSetRuntimeFormat(r *http.Request, format data.RuntimeFormat) *http.Request {
+	ctx := context.WithValue(r.Context(), runtimeFormatContextKey, format)
+	return r.WithContext(ctx)
+}
+
+// contextGetRuntimeFormat retrieves the runtime format requested by the client,
+// or an empty string if the client didn't ask for any.
+func (app *application) contextGetRuntimeFormat(r *http.Request) data.RuntimeFormat {
+	format, _ := r.Context().Value(runtimeFormatContextKey).(data.RuntimeFormat)
+	return format
+}
+
+// formatRuntimes sets the movies to encode their runtimes in the format
+// requested by the client. Nil movies are skipped.
+func (app *application) formatRuntimes(r *http.Request, movies ...*data.Movie) {
+	format := app.contextGetRuntimeFormat(r)
+	for _, movie := range movies {
+		if movie != nil {
+			movie.RuntimeFormat = format
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/walkccc/greenlight/internal/data"
 )
 
 // logError is a generic helper for logging an error message along with the
@@ -77,6 +79,19 @@ func (app *application) failedValidationResponse(
 	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
 }
 
+// payloadTooLargeResponse sends a 413 Request Entity Too Large status code and
+// JSON response to the client.
+func (app *application) payloadTooLargeResponse(w http.ResponseWriter, r *http.Request, limit int64) {
+	message := fmt.Sprintf("The request body must not be larger than %d bytes.", limit)
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
+// unsupportedMediaTypeResponse sends a 415 Unsupported Media Type status code
+// and JSON response to the client.
+func (app *application) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusUnsupportedMediaType, message)
+}
+
 // editConflictResponse sends a 409 Conflict status code and JSON response to
 // the client.
 func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Request) {
@@ -84,6 +99,24 @@ func (app *application) editConflictResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusConflict, message)
 }
 
+// duplicateMovieResponse sends a 409 Conflict status code and JSON response to
+// the client, listing the existing movies that the submitted one is likely to
+// be a duplicate of.
+func (app *application) duplicateMovieResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	message string,
+	candidates []*data.Movie,
+) {
+	env := envelope{"error": message, "candidates": candidates}
+
+	err := app.writeJSON(w, http.StatusConflict, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 // rateLimitExceededResponse sends a 429 Too Many Requests status code and JSON
 // response to the client.
 func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
@@ -126,3 +159,23 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	message := "Your user account doesn't have the necessary permissions to access this resource."
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
+
+// preconditionFailedResponse sends a 412 Precondition Failed status code and
+// JSON response to the client.
+func (app *application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "The resource has been modified since you last fetched it, please fetch it and try again."
+	app.errorResponse(w, r, http.StatusPreconditionFailed, message)
+}
+
+// preconditionRequiredResponse sends a 428 Precondition Required status code
+// and JSON response to the client.
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "This request must be conditional, please include an If-Match header."
+	app.errorResponse(w, r, http.StatusPreconditionRequired, message)
+}
+
+// patchTestFailedResponse sends a 409 Conflict status code and JSON response to
+// the client when a "test" operation of a JSON patch doesn't match.
+func (app *application) patchTestFailedResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusConflict, err.Error())
+}
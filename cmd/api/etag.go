@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/walkccc/greenlight/internal/data"
+)
+
+// movieETag returns a strong entity tag for a movie. Every edit to a movie,
+// including moving it to the trash and back, increments its version, and the
+// rating summary, the poster and the moderation status are the only fields
+// that change without an edit, so together with the id they are enough to
+// identify a representation of it. Poster keys are unique per upload.
+// Localized movies also include the language and a hash of the translated
+// text, since translations are edited separately from the movie. Likewise,
+// movies shown with their collection summary include the collection's version
+// and the movie's place in it.
+func movieETag(movie *data.Movie) string {
+	poster := ""
+	if movie.Poster != nil {
+		poster = "-" + path.Base(path.Dir(movie.Poster.Key))
+	}
+
+	// Moderation doesn't bump the version, so the status tells apart the
+	// same version before and after review.
+	status := ""
+	if movie.Status != "" && movie.Status != data.MovieStatusPublished {
+		status = "-" + string(movie.Status)
+	}
+
+	translation := ""
+	if movie.Language != "" {
+		hash := fnv.New32a()
+		fmt.Fprintf(hash, "%s\x00%s", movie.Title, movie.Synopsis)
+		translation = fmt.Sprintf("-%s-%08x", movie.Language, hash.Sum32())
+	}
+
+	collection := ""
+	if c := movie.Collection; c != nil {
+		collection = fmt.Sprintf("-c%d.%d.%d.%d", c.ID, c.Version, c.Position, c.Size)
+	}
+
+	return fmt.Sprintf(
+		`"%d-%d-%d-%.2f%s%s%s%s"`,
+		movie.ID, movie.Version, movie.RatingCount, movie.RatingAverage, poster, status, translation, collection,
+	)
+}
+
+// variantETag returns the entity tag of a variant of a movie representation,
+// given the entity tag of the full one. Movies narrowed down with the "fields"
+// parameter, and runtimes in any other than the default format, make for
+// different representations, so each combination gets a tag of its own.
+func (app *application) variantETag(r *http.Request, etag string, view movieView) string {
+	variant := ""
+
+	if len(view.Fields) > 0 {
+		// The fields are listed in any order, but always encoded the same way.
+		fields := slices.Clone(view.Fields)
+		slices.Sort(fields)
+
+		hash := fnv.New32a()
+		fmt.Fprint(hash, strings.Join(slices.Compact(fields), ","))
+		variant += fmt.Sprintf("-f%08x", hash.Sum32())
+	}
+
+	if format := app.contextGetRuntimeFormat(r); format != "" && format != data.RuntimeFormatMinutes {
+		variant += "-" + string(format)
+	}
+
+	if variant == "" {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + variant + `"`
+}
+
+// moviesETag returns a weak entity tag for a page of movies, derived from the
+// entity tag of every movie on the page and the pagination metadata.
+func moviesETag(movies []*data.Movie, metadata data.Metadata) string {
+	hash := sha256.New()
+	for _, movie := range movies {
+		fmt.Fprintf(hash, "%s,", movieETag(movie))
+	}
+	fmt.Fprintf(hash, "%+v", metadata)
+	return fmt.Sprintf(`W/"%s"`, hex.EncodeToString(hash.Sum(nil))[:32])
+}
+
+// matchETag reports whether etag matches any of the entity tags in the value of
+// an If-Match or If-None-Match header. A weak comparison ignores the "W/"
+// prefix, while a strong comparison never matches a weak tag.
+func matchETag(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notModified checks the If-None-Match header of a GET request against the
+// current etag of the resource. If it matches, a 304 Not Modified response is
+// sent and true is returned, and the handler should return without writing
+// the body.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchETag(header, etag, true) {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// preconditionsMet checks the If-Match header of a state-changing request
+// against the current etag of the resource. The header is required, so a
+// missing header results in a 428 Precondition Required response and a
+// mismatch results in a 412 Precondition Failed response. In both cases false
+// is returned and the handler should return straight away. The header matches
+// if it matches any of the given etags.
+func (app *application) preconditionsMet(w http.ResponseWriter, r *http.Request, etags ...string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		app.preconditionRequiredResponse(w, r)
+		return false
+	}
+
+	for _, etag := range etags {
+		if matchETag(header, etag, false) {
+			return true
+		}
+	}
+
+	app.preconditionFailedResponse(w, r)
+	return false
+}
+
+// moviePreconditionsMet checks the If-Match header of a state-changing request
+// against the movie's etag. Clients may have fetched the movie localized, or
+// along with its collection summary, so the etags of those representations are
+// accepted as well, in the runtime format of the request. The movie itself is
+// left unlocalized.
+func (app *application) moviePreconditionsMet(w http.ResponseWriter, r *http.Request, movie *data.Movie) bool {
+	localized := *movie
+	err := app.localizeMovies(r, &localized)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	summary, err := app.readMovieCollection(movie.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	collected, localizedCollected := *movie, localized
+	collected.Collection, localizedCollected.Collection = summary, summary
+
+	etags := []string{
+		movieETag(movie), movieETag(&localized), movieETag(&collected), movieETag(&localizedCollected),
+	}
+	for i := range etags {
+		etags[i] = app.variantETag(r, etags[i], movieView{})
+	}
+
+	return app.preconditionsMet(w, r, etags...)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// movieFieldSafeValues holds the fields of a movie that the "fields" parameter
+// can select.
+var movieFieldSafeValues = []string{
+	"id", "title", "year", "runtime", "genres", "version", "rating_average", "rating_count",
+	"poster", "external_ids", "status", "publish_at", "unpublish_at", "collection",
+	"original_title", "synopsis", "language",
+}
+
+// movieIncludeSafeValues holds the related data that the "include" parameter
+// can embed into movies.
+var movieIncludeSafeValues = []string{"credits", "releases", "translations"}
+
+// movieView holds the parts of movies that a client asked for with the
+// "fields" and "include" parameters. The zero value selects every field and
+// embeds nothing.
+type movieView struct {
+	Fields  []string
+	Include []string
+}
+
+// readMovieView reads the "fields" and "include" query string parameters,
+// checking each element against its safe values.
+func (app *application) readMovieView(qs url.Values, v *validator.Validator) movieView {
+	return movieView{
+		Fields:  app.readSafeCSV(qs, "fields", movieFieldSafeValues, v),
+		Include: app.readSafeCSV(qs, "include", movieIncludeSafeValues, v),
+	}
+}
+
+// readSafeCSV reads a comma-separated list from the query string, and records
+// an error in the Validator for the first element that isn't a safe value.
+func (app *application) readSafeCSV(qs url.Values, key string, safeValues []string, v *validator.Validator) []string {
+	values := app.readCSV(qs, key, []string{})
+
+	for _, value := range values {
+		if !validator.PermittedValue(value, safeValues...) {
+			v.AddError(key, fmt.Sprintf("contains unsupported value: %s", value))
+			break
+		}
+	}
+
+	return values
+}
+
+// presentMovies returns the representations of the movies selected by the
+// view, with the requested related data embedded. Without fields and includes,
+// the movies are returned as they are. Either way, runtimes are encoded in the
+// format that the client asked for.
+func (app *application) presentMovies(r *http.Request, view movieView, movies ...*data.Movie) ([]any, error) {
+	app.formatRuntimes(r, movies...)
+
+	representations := make([]any, len(movies))
+
+	if len(view.Fields) == 0 && len(view.Include) == 0 {
+		for i, movie := range movies {
+			representations[i] = movie
+		}
+		return representations, nil
+	}
+
+	embeds, err := app.readMovieEmbeds(view.Include, movies)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, movie := range movies {
+		js, err := json.Marshal(movie)
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(js, &fields)
+		if err != nil {
+			return nil, err
+		}
+
+		representation := make(map[string]any)
+		for name, value := range fields {
+			if len(view.Fields) == 0 || slices.Contains(view.Fields, name) {
+				representation[name] = value
+			}
+		}
+		for _, name := range view.Include {
+			representation[name] = embeds[name][movie.ID]
+		}
+
+		representations[i] = representation
+	}
+
+	return representations, nil
+}
+
+// readMovieEmbeds reads the related data to include in the movies, keyed by
+// the name of the relation and then by movie ID. Every movie gets an entry,
+// empty if it has no related data, so that clients can tell it apart from a
+// relation that wasn't included.
+func (app *application) readMovieEmbeds(include []string, movies []*data.Movie) (map[string]map[int64]any, error) {
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	embeds := make(map[string]map[int64]any)
+
+	if slices.Contains(include, "credits") {
+		credits, err := app.models.Credits.GetAllForMovies(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		byMovie := make(map[int64][]*data.Credit)
+		for _, id := range ids {
+			byMovie[id] = []*data.Credit{}
+		}
+		for _, credit := range credits {
+			byMovie[credit.MovieID] = append(byMovie[credit.MovieID], credit)
+		}
+
+		embeds["credits"] = make(map[int64]any)
+		for id, credits := range byMovie {
+			embeds["credits"][id] = credits
+		}
+	}
+
+	if slices.Contains(include, "releases") {
+		releases, err := app.models.Releases.GetAllForMovies(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		byMovie := make(map[int64][]*data.Release)
+		for _, id := range ids {
+			byMovie[id] = []*data.Release{}
+		}
+		for _, release := range releases {
+			byMovie[release.MovieID] = append(byMovie[release.MovieID], release)
+		}
+
+		embeds["releases"] = make(map[int64]any)
+		for id, releases := range byMovie {
+			embeds["releases"][id] = releases
+		}
+	}
+
+	if slices.Contains(include, "translations") {
+		translations, err := app.models.Translations.GetAllForMovies(ids)
+		if err != nil {
+			return nil, err
+		}
+
+		byMovie := make(map[int64][]*data.MovieTranslation)
+		for _, id := range ids {
+			byMovie[id] = []*data.MovieTranslation{}
+		}
+		for _, translation := range translations {
+			byMovie[translation.MovieID] = append(byMovie[translation.MovieID], translation)
+		}
+
+		embeds["translations"] = make(map[int64]any)
+		for id, translations := range byMovie {
+			embeds["translations"][id] = translations
+		}
+	}
+
+	return embeds, nil
+}
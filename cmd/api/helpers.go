@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/walkccc/greenlight/internal/validator"
@@ -28,6 +30,34 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// readVersionParam retrieves the "version" URL parameter from the current
+// request context and converts it to a movie version number. If the operation
+// isn't successful, return 0 and an error.
+func (app *application) readVersionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	version, err := strconv.ParseInt(params.ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+
+	return int32(version), nil
+}
+
+// readMovieIDParam retrieves the "movie_id" URL parameter from the current
+// request context and converts it to an integer. If the operation isn't
+// successful, return 0 and an error.
+func (app *application) readMovieIDParam(r *http.Request) (int64, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := strconv.ParseInt(params.ByName("movie_id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("invalid movie_id parameter")
+	}
+
+	return id, nil
+}
+
 type envelope map[string]any
 
 // writeJSON takes the destination http.ResponseWriter, the HTTP status code to
@@ -144,6 +174,81 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any
 	return nil
 }
 
+// readBody reads the whole request body, limited to 1MB like readJSON(), for
+// handlers which need the raw bytes rather than a decoded value.
+func (app *application) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	maxBytes := 1_048_576
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		default:
+			return nil, err
+		}
+	}
+
+	if len(body) == 0 {
+		return nil, errors.New("body must not be empty")
+	}
+
+	return body, nil
+}
+
+// mediaType returns the media type of the request's Content-Type header
+// without any parameters, e.g. "application/json" for
+// "application/json; charset=utf-8". If the header is missing or malformed, it
+// returns the empty string.
+func (app *application) mediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// extendDeadlines replaces the server-wide read and write timeouts for the
+// current request only, for requests such as imports and exports which are
+// expected to take far longer than usual.
+func (app *application) extendDeadlines(w http.ResponseWriter, timeout time.Duration) error {
+	rc := http.NewResponseController(w)
+
+	err := rc.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+
+	return rc.SetWriteDeadline(time.Now().Add(timeout))
+}
+
+// readFormFile returns the part of a multipart form with the given name, which
+// is read without buffering the form to memory or disk. Parts before it are
+// skipped.
+func (app *application) readFormFile(r *http.Request, name string) (io.Reader, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("multipart form must contain a %q part", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == name {
+			return part, nil
+		}
+	}
+}
+
 // readString returns a string value from the query string. If no matching key
 // can be found, it returns the default value.
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -189,6 +294,30 @@ func (app *application) readInt(
 	return i
 }
 
+// readBool reads a string value from the query string and converts it to a
+// boolean. If no matching key can be found, it returns the default value. If
+// the value can't be converted to a boolean, then it records an error message
+// in the provided Validator instance.
+func (app *application) readBool(
+	qs url.Values,
+	key string,
+	defaultValue bool,
+	v *validator.Validator,
+) bool {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
 // background accepts an arbitrary function as a parameter and launches a
 // background goroutine that is capable of recovering from any panics that may
 // occur.
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// startJobs launches the periodic background jobs of the application.
+func (app *application) startJobs() {
+	app.runPeriodically("purge trashed movies", app.config.trash.purgeInterval, app.purgeTrashedMovies)
+	app.runPeriodically("refresh related movies", app.config.related.refreshInterval, app.refreshRelatedMovies)
+	app.runPeriodically("publish scheduled movies", app.config.publishing.interval, app.publishScheduledMovies)
+}
+
+// runPeriodically launches a background goroutine which calls fn right away,
+// then once every interval for as long as the application is running. Any
+// error or panic is logged so that a single failed run doesn't stop the job.
+func (app *application) runPeriodically(name string, interval time.Duration, fn func() error) {
+	run := func() {
+		// Recover any panic.
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.Error(fmt.Sprintf("%v", err), "job", name)
+			}
+		}()
+
+		err := fn()
+		if err != nil {
+			app.logger.Error(err.Error(), "job", name)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		run()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+// purgeTrashedMovies permanently deletes the movies which have been in the
+// trash for longer than the configured retention period, along with their
+// poster images.
+func (app *application) purgeTrashedMovies() error {
+	count, posters, err := app.models.Movies.Purge(time.Now().Add(-app.config.trash.retention))
+	if err != nil {
+		return err
+	}
+
+	for _, poster := range posters {
+		app.deletePosterImages(poster)
+	}
+
+	if count > 0 {
+		app.logger.Info("Purged trashed movies.", "count", count)
+	}
+
+	return nil
+}
+
+// refreshRelatedMovies recomputes the related movies of the whole catalogue.
+func (app *application) refreshRelatedMovies() error {
+	start := time.Now()
+
+	count, err := app.models.Related.Refresh(app.config.related.size)
+	if err != nil {
+		return err
+	}
+
+	app.logger.Info("Refreshed related movies.", "count", count, "duration", time.Since(start).String())
+
+	return nil
+}
+
+// publishScheduledMovies announces the movies that went live since the last
+// run, whether their publication date has come or they were published right
+// away, with a "Movie went live." event per movie.
+func (app *application) publishScheduledMovies() error {
+	movies, err := app.models.Movies.MarkLive()
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		app.logger.Info(
+			"Movie went live.",
+			"id", movie.ID,
+			"title", movie.Title,
+			"publish_at", movie.PublishAt.Format(time.RFC3339),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// readList retrieves the list named by the "id" URL parameter, where the value
+// "watchlist" stands for the current user's default list. Lists owned by other
+// users are only returned if they are public and the caller is reading them;
+// otherwise data.ErrRecordNotFound is returned, so that private lists can't be
+// told apart from lists that don't exist.
+func (app *application) readList(r *http.Request, write bool) (*data.List, error) {
+	user := app.contextGetUser(r)
+
+	params := httprouter.ParamsFromContext(r.Context())
+	if params.ByName("id") == "watchlist" {
+		return app.models.Lists.GetWatchlist(user.ID)
+	}
+
+	id, err := app.readIDParam(r)
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+
+	list, err := app.models.Lists.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if list.UserID != user.ID && (write || !list.Public) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return list, nil
+}
+
+// getListsHandler handles requests for "GET /v1/lists", which return the
+// current user's lists, starting with their watchlist.
+func (app *application) getListsHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	// Make sure the watchlist exists before listing.
+	_, err := app.models.Lists.GetWatchlist(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	lists, err := app.models.Lists.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"lists": lists}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createListHandler handles requests for "POST /v1/lists".
+func (app *application) createListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name   string `json:"name"`
+		Public bool   `json:"public"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	list := &data.List{
+		UserID: app.contextGetUser(r).ID,
+		Name:   input.Name,
+		Public: input.Public,
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Create(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateList):
+			v.AddError("name", "A list with this name already exists.")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getListHandler handles requests for "GET /v1/lists/:id", which return the
+// list along with a page of its entries.
+func (app *application) getListHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "position")
+
+	input.Filters.SortSafeValues = []string{
+		"position", "added_at", "title", "year",
+		"-position", "-added_at", "-title", "-year",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	list, err := app.readList(r, false)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	entries, metadata, err := app.models.Lists.GetEntries(list.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, entry := range entries {
+		app.formatRuntimes(r, entry.Movie)
+	}
+
+	err = app.writeJSON(
+		w,
+		http.StatusOK,
+		envelope{"list": list, "entries": entries, "metadata": metadata},
+		nil,
+	)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateListHandler handles requests for "PATCH /v1/lists/:id", which rename a
+// list or share it by making it public.
+func (app *application) updateListHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.readList(r, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name   *string `json:"name"`
+		Public *bool   `json:"public"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		list.Name = *input.Name
+	}
+	if input.Public != nil {
+		list.Public = *input.Public
+	}
+
+	v := validator.New()
+
+	if data.ValidateList(v, list); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Update(list)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateList):
+			v.AddError("name", "A list with this name already exists.")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"list": list}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteListHandler handles requests for "DELETE /v1/lists/:id". The watchlist
+// can't be deleted.
+func (app *application) deleteListHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.readList(r, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if list.Default {
+		v := validator.New()
+		v.AddError("list", "The watchlist can't be deleted.")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.Delete(list.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "list successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// addListEntryHandler handles requests for "POST /v1/lists/:id/entries". The
+// movie is appended to the list unless a position is given.
+func (app *application) addListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.readList(r, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		MovieID   int64      `json:"movie_id"`
+		Position  int32      `json:"position"`
+		Watched   bool       `json:"watched"`
+		WatchedAt *time.Time `json:"watched_at"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.MovieID > 0, "movie_id", "must be provided")
+
+	entry := &data.ListEntry{
+		ListID:    list.ID,
+		Position:  input.Position,
+		Watched:   input.Watched,
+		WatchedAt: input.WatchedAt,
+	}
+	if entry.Watched && entry.WatchedAt == nil {
+		now := time.Now()
+		entry.WatchedAt = &now
+	}
+
+	if data.ValidateListEntry(v, entry); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	entry.Movie, err = app.readVisibleMovie(r, input.MovieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("movie_id", "must reference an existing movie")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Lists.AddEntry(entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateListEntry):
+			v.AddError("movie_id", "This movie is already on the list.")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.formatRuntimes(r, entry.Movie)
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"entry": entry}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateListEntryHandler handles requests for
+// "PATCH /v1/lists/:id/entries/:movie_id", which move an entry to another
+// position or mark the movie as watched or unwatched.
+func (app *application) updateListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.readList(r, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movieID, err := app.readMovieIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	entry, err := app.models.Lists.GetEntry(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Position  *int32     `json:"position"`
+		Watched   *bool      `json:"watched"`
+		WatchedAt *time.Time `json:"watched_at"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Position != nil {
+		entry.Position = *input.Position
+	}
+	if input.Watched != nil {
+		entry.Watched = *input.Watched
+		if !entry.Watched {
+			entry.WatchedAt = nil
+		} else if entry.WatchedAt == nil {
+			now := time.Now()
+			entry.WatchedAt = &now
+		}
+	}
+	if input.WatchedAt != nil {
+		entry.WatchedAt = input.WatchedAt
+	}
+
+	v := validator.New()
+
+	if data.ValidateListEntry(v, entry); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Lists.UpdateEntry(entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	entry.Movie = movie
+
+	app.formatRuntimes(r, entry.Movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"entry": entry}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeListEntryHandler handles requests for
+// "DELETE /v1/lists/:id/entries/:movie_id".
+func (app *application) removeListEntryHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.readList(r, true)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movieID, err := app.readMovieIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Lists.RemoveEntry(list.ID, movieID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "entry successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
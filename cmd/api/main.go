@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/walkccc/greenlight/internal/data"
 	"github.com/walkccc/greenlight/internal/mailer"
+	"github.com/walkccc/greenlight/internal/storage"
 	"github.com/walkccc/greenlight/internal/vcs"
 )
 
@@ -48,6 +50,29 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	trash struct {
+		retention     time.Duration
+		purgeInterval time.Duration
+	}
+	storage struct {
+		backend string // local|s3
+		dir     string
+		url     string
+		s3      storage.S3Config
+	}
+	posters struct {
+		maxSize int64
+	}
+	related struct {
+		size            int
+		refreshInterval time.Duration
+	}
+	stats struct {
+		cacheTTL time.Duration
+	}
+	publishing struct {
+		interval time.Duration
+	}
 }
 
 // application holds the dependencies for out HTTP handlers, helpers, and middleware.
@@ -56,6 +81,8 @@ type application struct {
 	logger *slog.Logger
 	models data.Models
 	mailer mailer.Mailer
+	blobs  storage.BlobStore
+	stats  *statsCache
 	wg     sync.WaitGroup
 }
 
@@ -99,6 +126,58 @@ func main() {
 		},
 	)
 
+	flag.DurationVar(
+		&cfg.trash.retention,
+		"trash-retention",
+		30*24*time.Hour,
+		"How long deleted movies are kept in the trash before being purged",
+	)
+	flag.DurationVar(
+		&cfg.trash.purgeInterval,
+		"trash-purge-interval",
+		time.Hour,
+		"How often the trash is checked for movies to purge",
+	)
+
+	flag.IntVar(&cfg.related.size, "related-size", 20, "Number of related movies stored per movie")
+	flag.DurationVar(
+		&cfg.related.refreshInterval,
+		"related-refresh-interval",
+		time.Hour,
+		"How often the related movies are recomputed",
+	)
+
+	flag.DurationVar(&cfg.stats.cacheTTL, "stats-cache-ttl", time.Minute, "How long movie statistics are cached")
+
+	flag.DurationVar(
+		&cfg.publishing.interval,
+		"publishing-interval",
+		time.Minute,
+		"How often scheduled movies are checked for going live",
+	)
+
+	flag.StringVar(&cfg.storage.backend, "storage", "local", "Blob storage backend (local|s3)")
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory of the local blob storage")
+	flag.StringVar(
+		&cfg.storage.url,
+		"storage-url",
+		"http://localhost:4000/uploads",
+		"Public base URL of the local blob storage",
+	)
+	flag.StringVar(&cfg.storage.s3.Endpoint, "s3-endpoint", "", "S3-compatible storage endpoint")
+	flag.StringVar(&cfg.storage.s3.Region, "s3-region", "us-east-1", "S3 region")
+	flag.StringVar(&cfg.storage.s3.Bucket, "s3-bucket", "greenlight", "S3 bucket")
+	flag.StringVar(&cfg.storage.s3.AccessKey, "s3-access-key", "", "S3 access key")
+	flag.StringVar(&cfg.storage.s3.SecretKey, "s3-secret-key", "", "S3 secret key")
+	flag.StringVar(
+		&cfg.storage.s3.PublicURL,
+		"s3-public-url",
+		"",
+		"Public base URL of the S3 bucket, if it isn't served from the endpoint",
+	)
+
+	flag.Int64Var(&cfg.posters.maxSize, "poster-max-size", 10<<20, "Maximum size of poster uploads in bytes")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -108,6 +187,22 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The background jobs tick at these intervals, which must be positive.
+	for _, f := range []struct {
+		name     string
+		interval time.Duration
+	}{
+		{"trash-purge-interval", cfg.trash.purgeInterval},
+		{"related-refresh-interval", cfg.related.refreshInterval},
+		{"publishing-interval", cfg.publishing.interval},
+	} {
+		if f.interval <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid value %q for flag -%s: must be positive\n", f.interval, f.name)
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	db, err := openDB(cfg)
@@ -117,6 +212,16 @@ func main() {
 	}
 	defer db.Close()
 
+	// Maintenance commands, such as backups, run instead of the server.
+	if flag.NArg() > 0 {
+		err = runCommand(db, logger, flag.Args())
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	expvar.NewString("version").Set(version)
 	expvar.Publish("goroutines", expvar.Func(func() any {
 		return runtime.NumGoroutine()
@@ -130,6 +235,12 @@ func main() {
 
 	logger.Info("Database connection pool established.")
 
+	blobs, err := openBlobStore(cfg)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
 	app := &application{
 		config: cfg,
 		logger: logger,
@@ -141,8 +252,12 @@ func main() {
 			cfg.smtp.password,
 			cfg.smtp.sender,
 		),
+		blobs: blobs,
+		stats: newStatsCache(cfg.stats.cacheTTL),
 	}
 
+	app.startJobs()
+
 	err = app.serve()
 	if err != nil {
 		logger.Error(err.Error())
@@ -172,3 +287,18 @@ func openDB(cfg config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// openBlobStore returns the blob store selected by the configuration.
+func openBlobStore(cfg config) (storage.BlobStore, error) {
+	switch cfg.storage.backend {
+	case "local":
+		return storage.NewLocalStore(cfg.storage.dir, cfg.storage.url)
+	case "s3":
+		if cfg.storage.s3.Endpoint == "" {
+			return nil, errors.New("-s3-endpoint must be provided for s3 storage")
+		}
+		return storage.NewS3Store(cfg.storage.s3), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.storage.backend)
+	}
+}
@@ -240,13 +240,17 @@ func (app *application) enableCORS(next http.Handler) http.Handler {
 			for _, trustedOrigin := range app.config.cors.trustedOrigins {
 				if origin == trustedOrigin {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 					// Treat it as a preflight request.
 					if r.Method == http.MethodOptions &&
 						r.Header.Get("Access-Control-Request-Method") != "" {
 						// Set the necessary preflight response headers.
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set(
+							"Access-Control-Allow-Headers",
+							"Authorization, Content-Type, If-Match, If-None-Match",
+						)
 
 						// Return from the middleware with no further action.
 						w.WriteHeader(http.StatusOK)
@@ -348,3 +352,38 @@ func (app *application) metrics(next http.Handler) http.Handler {
 		totalProcessingTimeMicroseconds.Add(duration)
 	})
 }
+
+// negotiateRuntimeFormat reads the representation of runtimes that the client
+// wants in responses from the "runtime_format" query string parameter, or else
+// from the Runtime-Format header, and adds it to the request context for the
+// handlers that write movies. Runtimes are formatted as "<runtime> mins" unless
+// either is given.
+func (app *application) negotiateRuntimeFormat(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Runtime-Format")
+
+		format := r.URL.Query().Get("runtime_format")
+		if format == "" {
+			format = r.Header.Get("Runtime-Format")
+		}
+
+		if format == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		v := validator.New()
+
+		v.Check(
+			validator.PermittedValue(format, data.RuntimeFormats...),
+			"runtime_format",
+			"must be one of minutes, integer or iso8601",
+		)
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		next.ServeHTTP(w, app.contextSetRuntimeFormat(r, data.RuntimeFormat(format)))
+	})
+}
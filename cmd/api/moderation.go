@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// canModerate reports whether the current user holds the "movies:moderate"
+// permission. Moderators see movies whatever their status, and the movies they
+// submit are published without review.
+func (app *application) canModerate(r *http.Request) (bool, error) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		return false, nil
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include("movies:moderate"), nil
+}
+
+// submissionStatus returns the status of the movies that the current user
+// creates: moderators publish them straight away, while everyone else's wait
+// in the moderation queue.
+func (app *application) submissionStatus(r *http.Request) (data.MovieStatus, error) {
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		return "", err
+	}
+
+	return submissionStatusFor(moderator), nil
+}
+
+// submissionStatusFor returns the status of the movies created by a user who
+// is a moderator or not.
+func submissionStatusFor(moderator bool) data.MovieStatus {
+	if moderator {
+		return data.MovieStatusPublished
+	}
+	return data.MovieStatusPending
+}
+
+// resubmitEditedMovie sends a published movie back to the moderation queue
+// when it is edited by someone who isn't a moderator, so that edits are
+// reviewed like submissions are. Movies that aren't published keep their
+// status. The change is saved along with the edit by MovieModel.Update.
+func resubmitEditedMovie(movie *data.Movie, moderator bool) {
+	if movie.Status == data.MovieStatusPublished && !moderator {
+		movie.Status = data.MovieStatusPending
+	}
+}
+
+// readVisibleMovie retrieves a movie as the current user may see it, following
+// the rules of checkMovieVisible. Every handler that reads or writes a single
+// movie should load it this way.
+func (app *application) readVisibleMovie(r *http.Request, id int64) (*data.Movie, error) {
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMovieVisible(movie, app.contextGetUser(r).ID, moderator)
+	if err != nil {
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// checkMovieVisible checks that the user with the given id may see the movie.
+// Movies that aren't live are only shown to moderators and to their submitter,
+// and data.ErrRecordNotFound is returned to everyone else, so that they can't
+// be told apart from movies that don't exist. Likewise, who submitted a movie
+// and why it was moderated is only shown to them, and is cleared otherwise.
+func checkMovieVisible(movie *data.Movie, userID int64, moderator bool) error {
+	if moderator || (movie.SubmittedBy != nil && *movie.SubmittedBy == userID) {
+		return nil
+	}
+
+	if !movie.IsLive(time.Now()) {
+		return data.ErrRecordNotFound
+	}
+
+	movie.SubmittedBy = nil
+	movie.ModerationReason = ""
+	return nil
+}
+
+// movieCriteriaPermitted checks that the current user may list movies with the
+// status in the criteria, as only moderators may list unpublished movies. If
+// not, a 403 Forbidden response is sent and false is returned, and the handler
+// should return straight away.
+func (app *application) movieCriteriaPermitted(w http.ResponseWriter, r *http.Request, criteria data.MovieCriteria) bool {
+	if criteria.Status == data.MovieStatusPublished {
+		return true
+	}
+
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !moderator {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// submitMovieHandler handles requests for "POST /v1/movies/:id/submit", which
+// send a draft or a rejected movie to the moderation queue. Only the movie's
+// submitter and moderators may do so.
+func (app *application) submitMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if movie.Status != data.MovieStatusDraft && movie.Status != data.MovieStatusRejected {
+		v := validator.New()
+		v.AddError("status", "Only draft and rejected movies can be submitted for review.")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.moviePreconditionsMet(w, r, movie) {
+		return
+	}
+
+	from := movie.Status
+	movie.Status = data.MovieStatusPending
+	movie.ModerationReason = ""
+
+	app.setMovieStatus(w, r, movie, from)
+}
+
+// getModerationQueueHandler handles requests for "GET /v1/moderation/movies",
+// which return a page of the movies waiting for review, oldest first.
+func (app *application) getModerationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafeValues = []string{"id", "title", "-id", "-title"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	criteria := data.MovieCriteria{Status: data.MovieStatusPending}
+
+	movies, metadata, err := app.models.Movies.GetAll(criteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.formatRuntimes(r, movies...)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// approveMovieHandler handles requests for
+// "POST /v1/moderation/movies/:id/approve", which publish a pending movie. The
+// reason is optional. Like edits, decisions require an If-Match header with
+// the movie's current ETag, so that moderators only ever approve the version
+// they reviewed, even if the submitter edits the movie in the meantime.
+func (app *application) approveMovieHandler(w http.ResponseWriter, r *http.Request) {
+	app.moderateMovie(w, r, data.MovieStatusPublished)
+}
+
+// rejectMovieHandler handles requests for
+// "POST /v1/moderation/movies/:id/reject", which turn down a pending movie.
+// The reason is required, so that the submitter knows what to fix. As with
+// approvals, an If-Match header is required.
+func (app *application) rejectMovieHandler(w http.ResponseWriter, r *http.Request) {
+	app.moderateMovie(w, r, data.MovieStatusRejected)
+}
+
+// moderateMovie moves the pending movie named by the "id" URL parameter to the
+// given status, along with the reason in the request body.
+func (app *application) moderateMovie(w http.ResponseWriter, r *http.Request, status data.MovieStatus) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	// Approvals need no reason, so an empty body is fine.
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+
+	if status == data.MovieStatusRejected || input.Reason != "" {
+		data.ValidateModerationReason(v, input.Reason)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if movie.Status != data.MovieStatusPending {
+		v.AddError("status", "The movie isn't waiting for review.")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.moviePreconditionsMet(w, r, movie) {
+		return
+	}
+
+	movie.Status = status
+	movie.ModerationReason = input.Reason
+
+	app.setMovieStatus(w, r, movie, data.MovieStatusPending)
+}
+
+// setMovieStatus saves the movie's new status, provided that it is still from,
+// and sends the movie back. Callers check the If-Match header first, so if the
+// movie has changed since, the client's precondition no longer holds.
+func (app *application) setMovieStatus(w http.ResponseWriter, r *http.Request, movie *data.Movie, from data.MovieStatus) {
+	err := app.models.Movies.SetStatus(movie, from)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
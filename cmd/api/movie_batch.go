@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// batchMaxOperations limits the number of operations in a single batch.
+const batchMaxOperations = 100
+
+// errBatchAborted is returned from the transaction callback of an atomic batch
+// to roll it back after an operation has failed.
+var errBatchAborted = errors.New("batch aborted")
+
+// batchOperation holds a single operation of a batch request. Movie holds the
+// fields of a new movie for "create", and the fields to change for "update".
+// Version is required for "update" and "delete", which fail with a conflict
+// unless it matches the movie's current version, as If-Match does for single
+// requests.
+type batchOperation struct {
+	Op      string `json:"op"`
+	ID      int64  `json:"id"`
+	Version *int32 `json:"version"`
+	Movie   struct {
+		Title       *string          `json:"title"`
+		Year        *int32           `json:"year"`
+		Runtime     *data.Runtime    `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
+		PublishAt   nullableTime     `json:"publish_at"`
+		UnpublishAt nullableTime     `json:"unpublish_at"`
+	} `json:"movie"`
+}
+
+// batchResult holds the outcome of a single operation of a batch request. The
+// status is the one the equivalent single request would have responded with.
+type batchResult struct {
+	Op     string      `json:"op"`
+	ID     int64       `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Movie  *data.Movie `json:"movie,omitempty"`
+	Error  any         `json:"error,omitempty"`
+}
+
+func (res batchResult) failed() bool {
+	return res.Status >= 400
+}
+
+// batchMoviesHandler handles requests for "POST /v1/movies/batch". The
+// operations are run in order. When "atomic" is true, they run in a single
+// transaction, which is rolled back as soon as one of them fails; otherwise
+// each operation is committed on its own, regardless of the others.
+func (app *application) batchMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Atomic     bool             `json:"atomic"`
+		Operations []batchOperation `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(len(input.Operations) > 0, "operations", "must contain at least 1 operation")
+	v.Check(
+		len(input.Operations) <= batchMaxOperations,
+		"operations",
+		fmt.Sprintf("must not contain more than %d operations", batchMaxOperations),
+	)
+
+	for i, op := range input.Operations {
+		key := fmt.Sprintf("operations[%d]", i)
+
+		v.Check(
+			validator.PermittedValue(op.Op, "create", "update", "delete"),
+			key+".op",
+			"must be one of create, update or delete",
+		)
+		if op.Op == "update" || op.Op == "delete" {
+			v.Check(op.ID > 0, key+".id", "must be provided")
+			v.Check(op.Version != nil, key+".version", "must be provided")
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	results := make([]batchResult, len(input.Operations))
+
+	if !input.Atomic {
+		for i, op := range input.Operations {
+			results[i], err = app.runBatchOperation(app.models.Movies, op, genres, user.ID, moderator)
+			if err != nil {
+				app.logError(r, err)
+				results[i] = batchResult{
+					Op:     op.Op,
+					ID:     op.ID,
+					Status: http.StatusInternalServerError,
+					Error:  "The server encountered a problem and could not process this operation.",
+				}
+			}
+			app.formatRuntimes(r, results[i].Movie)
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	failed := -1
+
+	err = app.models.Movies.Transaction(func(movies data.MovieModelInterface) error {
+		for i, op := range input.Operations {
+			results[i], err = app.runBatchOperation(movies, op, genres, user.ID, moderator)
+			if err != nil {
+				return err
+			}
+
+			if results[i].failed() {
+				failed = i
+				return errBatchAborted
+			}
+			app.formatRuntimes(r, results[i].Movie)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if failed == -1 {
+		err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Nothing has been committed, so report every other operation as failing
+	// because of the one that did, and respond with the status of the latter.
+	for i, op := range input.Operations {
+		if i == failed {
+			continue
+		}
+
+		results[i] = batchResult{
+			Op:     op.Op,
+			ID:     op.ID,
+			Status: http.StatusFailedDependency,
+			Error:  fmt.Sprintf("Not applied because operation %d failed.", failed),
+		}
+	}
+
+	err = app.writeJSON(w, results[failed].Status, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// runBatchOperation runs a single operation of a batch request against the
+// given model, which may be bound to a transaction. Failures of the operation
+// itself are reported in the result, and the returned error is reserved for
+// unexpected errors. The same moderation rules as for single requests apply,
+// depending on whether the editor is a moderator.
+func (app *application) runBatchOperation(
+	movies data.MovieModelInterface,
+	op batchOperation,
+	genres data.GenreCatalogue,
+	editorID int64,
+	moderator bool,
+) (batchResult, error) {
+	result := batchResult{Op: op.Op, ID: op.ID}
+
+	var movie *data.Movie
+
+	if op.Op == "create" {
+		movie = &data.Movie{Status: submissionStatusFor(moderator)}
+	} else {
+		var err error
+
+		movie, err = movies.Get(op.ID)
+		if err == nil {
+			err = checkMovieVisible(movie, editorID, moderator)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				result.Status = http.StatusNotFound
+				result.Error = "The requested resource could not be found."
+				return result, nil
+			default:
+				return result, err
+			}
+		}
+
+		movie.Version = *op.Version
+	}
+
+	if op.Op == "delete" {
+		err := movies.Delete(movie.ID, movie.Version, editorID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				result.Status = http.StatusConflict
+				result.Error = "Unable to delete the record due to an edit conflict, please try again."
+				return result, nil
+			default:
+				return result, err
+			}
+		}
+
+		result.Status = http.StatusOK
+		return result, nil
+	}
+
+	if op.Movie.Title != nil {
+		movie.Title = *op.Movie.Title
+	}
+	if op.Movie.Year != nil {
+		movie.Year = *op.Movie.Year
+	}
+	if op.Movie.Runtime != nil {
+		movie.Runtime = *op.Movie.Runtime
+	}
+	if op.Movie.Genres != nil {
+		movie.Genres = op.Movie.Genres
+	}
+	mergeExternalIDs(movie, op.Movie.ExternalIDs)
+	if op.Movie.PublishAt.Set {
+		movie.PublishAt = op.Movie.PublishAt.Value
+	}
+	if op.Movie.UnpublishAt.Set {
+		movie.UnpublishAt = op.Movie.UnpublishAt.Value
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovie(v, movie, genres); !v.Valid() {
+		result.Status = http.StatusUnprocessableEntity
+		result.Error = v.Errors
+		return result, nil
+	}
+
+	if op.Op == "create" {
+		err := movies.Create(movie, editorID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrDuplicateExternalID):
+				result.Status = http.StatusConflict
+				result.Error = "Another movie already has one of these external identifiers."
+				return result, nil
+			default:
+				return result, err
+			}
+		}
+
+		result.ID = movie.ID
+		result.Status = http.StatusCreated
+		result.Movie = movie
+		return result, nil
+	}
+
+	resubmitEditedMovie(movie, moderator)
+
+	err := movies.Update(movie, editorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			result.Status = http.StatusConflict
+			result.Error = "Unable to update the record due to an edit conflict, please try again."
+			return result, nil
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			result.Status = http.StatusConflict
+			result.Error = "Another movie already has one of these external identifiers."
+			return result, nil
+		default:
+			return result, err
+		}
+	}
+
+	result.Status = http.StatusOK
+	result.Movie = movie
+	return result, nil
+}
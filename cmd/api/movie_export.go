@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// exportTimeout replaces the server's write timeout for exports, which stream
+// the whole catalogue and take far longer than regular requests.
+const exportTimeout = 10 * time.Minute
+
+// exportContentTypes maps the export formats to their Content-Type headers.
+var exportContentTypes = map[string]string{
+	data.FormatCSV:    "text/csv; charset=utf-8",
+	data.FormatTSV:    "text/tab-separated-values; charset=utf-8",
+	data.FormatNDJSON: "application/x-ndjson",
+	data.FormatJSON:   "application/json",
+}
+
+// exportMoviesHandler handles requests for "GET /v1/movies/export". It accepts
+// the same filters and sort parameter as getMoviesHandler, but rather than
+// returning a page of movies it streams every matching movie as CSV, TSV,
+// NDJSON or a single JSON document, without holding them in memory. Runtimes
+// are written in the runtime format that the client asked for, if any.
+func (app *application) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Format string
+		data.MovieCriteria
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Format = app.readString(qs, "format", data.FormatJSON)
+	input.MovieCriteria = app.readMovieCriteria(qs, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafeValues = movieSortSafeValues
+
+	v.Check(
+		validator.PermittedValue(
+			input.Format,
+			data.FormatCSV, data.FormatTSV, data.FormatNDJSON, data.FormatJSON,
+		),
+		"format",
+		"must be one of csv, tsv, ndjson or json",
+	)
+	data.ValidateSort(v, input.Filters)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.movieCriteriaPermitted(w, r, input.MovieCriteria) {
+		return
+	}
+
+	err := app.normalizeMovieCriteria(&input.MovieCriteria)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.extendDeadlines(w, exportTimeout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// The encoder is only created once the first movie has been read, so that
+	// errors from the query itself can still be sent as a regular error
+	// response.
+	var encoder data.MovieEncoder
+
+	start := func() error {
+		w.Header().Set("Content-Type", exportContentTypes[input.Format])
+		w.Header().Set(
+			"Content-Disposition",
+			fmt.Sprintf(`attachment; filename="movies.%s"`, input.Format),
+		)
+
+		encoder, err = data.NewMovieEncoder(input.Format, w, app.contextGetRuntimeFormat(r))
+		return err
+	}
+
+	err = app.models.Movies.Export(input.MovieCriteria, input.Filters, func(movie *data.Movie) error {
+		if encoder == nil {
+			err := start()
+			if err != nil {
+				return err
+			}
+		}
+
+		return encoder.Encode(movie)
+	})
+	if err != nil {
+		if encoder == nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// The response is already underway, so all we can do is log the error
+		// and leave the client with a truncated document.
+		app.logError(r, err)
+		return
+	}
+
+	if encoder == nil {
+		err = start()
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		app.logError(r, err)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+const (
+	// importMaxBytes limits the size of an import upload. Unlike readJSON(),
+	// uploads are streamed, so this doesn't translate into memory usage.
+	importMaxBytes = 256 << 20
+	// importBatchSize is the number of movies inserted per transaction.
+	importBatchSize = 1000
+	// importMaxRowErrors limits the number of row errors included in the
+	// report. Any further errors are only counted.
+	importMaxRowErrors = 1000
+	// importTimeout replaces the server's read and write timeouts for imports,
+	// which take far longer than regular requests.
+	importTimeout = 10 * time.Minute
+)
+
+// importFormats maps the accepted Content-Type media types to import formats.
+var importFormats = map[string]string{
+	"text/csv":                  data.FormatCSV,
+	"text/tab-separated-values": data.FormatTSV,
+	"application/x-ndjson":      data.FormatNDJSON,
+	"application/ndjson":        data.FormatNDJSON,
+}
+
+// importRowError holds the validation errors of a single row of an import.
+// Rows are numbered from 1, not counting the CSV or TSV header row.
+type importRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+// importReport summarizes the result of an import. Each batch is committed on
+// its own, so CommittedRows is the number of leading rows that are done with,
+// either imported or reported as invalid. An import that fails midway can be
+// resumed by sending the rows after them again.
+type importReport struct {
+	Format        string           `json:"format"`
+	DryRun        bool             `json:"dry_run"`
+	TotalRows     int              `json:"total_rows"`
+	ValidRows     int              `json:"valid_rows"`
+	Imported      int              `json:"imported"`
+	CommittedRows int              `json:"committed_rows"`
+	ErrorCount    int              `json:"error_count"`
+	Errors        []importRowError `json:"errors"`
+}
+
+// importMoviesHandler handles requests for "POST /v1/movies/import". The body
+// is a CSV, TSV or NDJSON stream (either raw, with the matching Content-Type
+// or a "format" query parameter, or as the "file" part of a multipart form).
+// Each row is validated like a newly created movie, and the valid rows are
+// inserted in batches. Invalid rows are skipped and listed in the report. With
+// "dry_run=true" the rows are only validated. If the import fails midway, the
+// report in the error response tells how many rows were committed.
+func (app *application) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	mediaType := app.mediaType(r)
+
+	format := app.readString(qs, "format", importFormats[mediaType])
+	dryRun := app.readBool(qs, "dry_run", false, v)
+
+	if mediaType == "multipart/form-data" && format == "" {
+		v.AddError("format", "must be provided for multipart uploads")
+	}
+	v.Check(
+		validator.PermittedValue(format, data.FormatCSV, data.FormatTSV, data.FormatNDJSON),
+		"format",
+		"must be one of csv, tsv or ndjson",
+	)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Uploads can easily take longer than the server-wide timeouts, so extend
+	// the deadlines for this request only.
+	err := app.extendDeadlines(w, importTimeout)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+
+	body, err := app.importBody(r, mediaType)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	decoder, err := data.NewMovieDecoder(format, body)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	status, err := app.submissionStatus(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	report := importReport{
+		Format: format,
+		DryRun: dryRun,
+		Errors: []importRowError{},
+	}
+
+	batch := make([]*data.Movie, 0, importBatchSize)
+
+	flush := func() error {
+		if dryRun || len(batch) == 0 {
+			return nil
+		}
+
+		err := app.models.Movies.Import(batch, user.ID, status)
+		if err != nil {
+			return err
+		}
+
+		report.Imported += len(batch)
+		report.CommittedRows = report.TotalRows
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		rowValidator := validator.New()
+
+		movie, err := decoder.Decode(rowValidator)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			app.importFailedResponse(w, r, http.StatusBadRequest, err, report)
+			return
+		}
+
+		report.TotalRows++
+
+		if movie != nil {
+			data.ValidateMovie(rowValidator, movie, genres)
+		}
+
+		if !rowValidator.Valid() {
+			report.ErrorCount++
+			if len(report.Errors) < importMaxRowErrors {
+				report.Errors = append(report.Errors, importRowError{
+					Row:    report.TotalRows,
+					Errors: rowValidator.Errors,
+				})
+			}
+			continue
+		}
+
+		report.ValidRows++
+		batch = append(batch, movie)
+
+		if len(batch) == importBatchSize {
+			err = flush()
+			if err != nil {
+				app.importFailedResponse(w, r, http.StatusInternalServerError, err, report)
+				return
+			}
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		app.importFailedResponse(w, r, http.StatusInternalServerError, err, report)
+		return
+	}
+
+	if !dryRun {
+		report.CommittedRows = report.TotalRows
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// importBody returns the stream to import from: the request body itself, or
+// the "file" part of a multipart form.
+func (app *application) importBody(r *http.Request, mediaType string) (io.Reader, error) {
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	return app.readFormFile(r, "file")
+}
+
+// importFailedResponse sends a JSON error response when the import can't go
+// on, along with the report of what had already been committed by then. The
+// status code is either 400 Bad Request, when the import stream can't be read
+// any further, or 500 Internal Server Error, when a batch can't be inserted.
+func (app *application) importFailedResponse(
+	w http.ResponseWriter,
+	r *http.Request,
+	statusCode int,
+	err error,
+	report importReport,
+) {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+	}
+
+	message := err.Error()
+	if statusCode == http.StatusInternalServerError {
+		app.logError(r, err)
+		message = "The server encountered a problem and could not process your request."
+	}
+
+	env := envelope{"error": message, "import": report}
+
+	err = app.writeJSON(w, statusCode, env, nil)
+	if err != nil {
+		app.logError(r, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// getMovieVersionsHandler handles requests for "GET /v1/movies/:id/versions".
+func (app *application) getMovieVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-version"
+	input.Filters.SortSafeValues = []string{"-version"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Make sure that the movie exists (and isn't in the trash) before listing
+	// its history.
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	versions, metadata, err := app.models.Versions.GetAllForMovie(id, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	format := app.contextGetRuntimeFormat(r)
+	for _, version := range versions {
+		version.RuntimeFormat = format
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"versions": versions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getMovieVersionHandler handles requests for
+// "GET /v1/movies/:id/versions/:version".
+func (app *application) getMovieVersionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movieVersion, err := app.models.Versions.Get(id, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movieVersion.RuntimeFormat = app.contextGetRuntimeFormat(r)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"version": movieVersion}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getMovieDiffHandler handles requests for "GET /v1/movies/:id/diff". It
+// compares the "from" version with the "to" version, which defaults to the
+// current version of the movie.
+func (app *application) getMovieDiffHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	from := app.readInt(qs, "from", 0, v)
+	to := app.readInt(qs, "to", int(movie.Version), v)
+
+	v.Check(from >= 1, "from", "must be provided")
+	v.Check(from <= int(movie.Version), "from", "must not be greater than the current version")
+	v.Check(to >= 1, "to", "must be greater than zero")
+	v.Check(to <= int(movie.Version), "to", "must not be greater than the current version")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	fromVersion, err := app.models.Versions.Get(id, int32(from))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	toVersion, err := app.models.Versions.Get(id, int32(to))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	fromVersion.RuntimeFormat = app.contextGetRuntimeFormat(r)
+	toVersion.RuntimeFormat = fromVersion.RuntimeFormat
+
+	env := envelope{
+		"from":    from,
+		"to":      to,
+		"changes": data.DiffMovieVersions(fromVersion, toVersion),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revertMovieHandler handles requests for "POST /v1/movies/:id/revert". The
+// movie's fields are restored from an older version and saved as a new
+// version, so the history itself is never rewritten. Like updates, reverts
+// require an If-Match header with the movie's current ETag. See
+// data.MovieVersion.ApplyTo for the fields that are restored.
+func (app *application) revertMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.moviePreconditionsMet(w, r, movie) {
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(input.Version >= 1, "version", "must be provided")
+	v.Check(input.Version < movie.Version, "version", "must be older than the current version")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movieVersion, err := app.models.Versions.Get(id, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("version", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movieVersion.ApplyTo(movie)
+
+	// The old version may reference genres which have since been renamed or
+	// merged, so it goes through the same validation as any other update.
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateMovie(v, movie, genres); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resubmitEditedMovie(movie, moderator)
+
+	user := app.contextGetUser(r)
+
+	// As for updates, a conflict means the movie has changed since the If-Match
+	// header was checked.
+	err = app.models.Movies.Update(movie, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,52 +1,147 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/jsonpatch"
 	"github.com/walkccc/greenlight/internal/validator"
 )
 
+// movieSortSafeValues holds the supported values of the "sort" parameter when
+// listing movies.
+var movieSortSafeValues = []string{
+	"id", "title", "year", "runtime", "rating_average", "rating_count",
+	"-id", "-title", "-year", "-runtime", "-rating_average", "-rating_count",
+}
+
 // getMoviesHandler handles requests for "GET /v1/movies".
 func (app *application) getMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Title  string
-		Genres []string
+		data.MovieCriteria
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Title = app.readString(qs, "title", "")
-	input.Genres = app.readCSV(qs, "genres", []string{})
+	input.MovieCriteria = app.readMovieCriteria(qs, v)
+	view := app.readMovieView(qs, v)
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
-
-	input.Filters.SortSafeValues = []string{
-		"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime",
-	}
+	input.Filters.SortSafeValues = movieSortSafeValues
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, metadata, err := app.models.Movies.GetAll(input.Title, input.Genres, input.Filters)
+	if !app.movieCriteriaPermitted(w, r, input.MovieCriteria) {
+		return
+	}
+
+	err := app.normalizeMovieCriteria(&input.MovieCriteria)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	movies, metadata, err := app.models.Movies.GetAll(input.MovieCriteria, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.localizeMovies(r, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	// The ETag only covers the movies themselves, so responses embedding
+	// related data are never validated against it.
+	headers := make(http.Header)
+	if len(view.Include) == 0 {
+		etag := app.variantETag(r, moviesETag(movies, metadata), view)
+		if app.notModified(w, r, etag) {
+			return
+		}
+		headers.Set("ETag", etag)
+	}
+
+	representations, err := app.presentMovies(r, view, movies...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": representations, "metadata": metadata}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
+// readMovieCriteria reads the query string parameters that select which movies
+// are listed or exported.
+func (app *application) readMovieCriteria(qs url.Values, v *validator.Validator) data.MovieCriteria {
+	criteria := data.MovieCriteria{
+		Title:        app.readString(qs, "title", ""),
+		Genres:       app.readCSV(qs, "genres", []string{}),
+		PersonID:     int64(app.readInt(qs, "person_id", 0, v)),
+		CollectionID: int64(app.readInt(qs, "collection_id", 0, v)),
+		Tags:         app.readCSV(qs, "tags", []string{}),
+		Status:       data.MovieStatus(app.readString(qs, "status", string(data.MovieStatusPublished))),
+		ReleasedIn:   strings.ToUpper(app.readString(qs, "released_in", "")),
+		Upcoming:     app.readBool(qs, "upcoming", false, v),
+	}
+
+	for i, tag := range criteria.Tags {
+		criteria.Tags[i] = data.Slugify(tag)
+	}
+
+	v.Check(criteria.PersonID >= 0, "person_id", "must be a positive integer")
+	v.Check(criteria.CollectionID >= 0, "collection_id", "must be a positive integer")
+	v.Check(
+		validator.PermittedValue(string(criteria.Status), data.MovieStatuses...),
+		"status",
+		"must be one of draft, pending, published or rejected",
+	)
+	if criteria.ReleasedIn != "" {
+		v.Check(validator.Matches(criteria.ReleasedIn, data.CountryRX), "released_in", "must be a valid ISO 3166-1 alpha-2 code")
+	}
+
+	if externalID := app.readString(qs, "external_id", ""); externalID != "" {
+		ids, ok := data.ParseExternalID(externalID)
+		v.Check(ok, "external_id", "must be a valid external identifier")
+		criteria.ExternalIDs = ids
+	}
+
+	return criteria
+}
+
+// normalizeMovieCriteria normalizes the genres in the criteria so that aliases
+// match the canonical slugs stored on the movies. Unknown genres are left
+// untouched and simply won't match anything.
+func (app *application) normalizeMovieCriteria(criteria *data.MovieCriteria) error {
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		return err
+	}
+
+	genres.Normalize(criteria.Genres)
+	return nil
+}
+
 // createMovieHandler handles requests for "POST /v1/movies".
 func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 	// Declare an anonymous struct to hold the information that we expect to be in
@@ -54,10 +149,14 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	// are a subset of the Movie struct that we created earlier). This struct will
 	// be our *target decode destination*.
 	var input struct {
-		Title   string       `json:"title"`
-		Year    int32        `json:"year"`
-		Runtime data.Runtime `json:"runtime"`
-		Genres  []string     `json:"genres"`
+		Title       string           `json:"title"`
+		Year        int32            `json:"year"`
+		Runtime     data.Runtime     `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
+		Status      data.MovieStatus `json:"status"`
+		PublishAt   *time.Time       `json:"publish_at"`
+		UnpublishAt *time.Time       `json:"unpublish_at"`
 	}
 
 	err := app.readJSON(w, r, &input)
@@ -67,27 +166,82 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	movie := &data.Movie{
-		Title:   input.Title,
-		Year:    input.Year,
-		Runtime: input.Runtime,
-		Genres:  input.Genres,
+		Title:       input.Title,
+		Year:        input.Year,
+		Runtime:     input.Runtime,
+		Genres:      input.Genres,
+		ExternalIDs: input.ExternalIDs,
+		Status:      input.Status,
+		PublishAt:   input.PublishAt,
+		UnpublishAt: input.UnpublishAt,
+	}
+
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	v := validator.New()
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
+	// Clients that have checked the candidates of a previous attempt can
+	// create the movie anyway with "?force=true".
+	force := app.readBool(r.URL.Query(), "force", false, v)
+
+	v.Check(
+		validator.PermittedValue(movie.Status, "", data.MovieStatusDraft, data.MovieStatusPending, data.MovieStatusPublished),
+		"status",
+		"must be one of draft, pending or published",
+	)
+
+	if data.ValidateMovie(v, movie, genres); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = app.models.Movies.Create(movie)
+	moderator, err := app.canModerate(r)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
+	// Drafts are kept as they are, while anything else is published straight
+	// away only for moderators, and sent for review otherwise.
+	if movie.Status != data.MovieStatusDraft {
+		movie.Status = submissionStatusFor(moderator)
+	}
+
+	if !force {
+		candidates, err := app.models.Movies.FindDuplicates(movie, !moderator)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if len(candidates) > 0 {
+			message := "A movie with the same title and year already exists, add ?force=true to create it anyway."
+			app.duplicateMovieResponse(w, r, message, candidates)
+			return
+		}
+	}
+
+	user := app.contextGetUser(r)
+
+	err = app.models.Movies.Create(movie, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			app.externalIDConflictResponse(w, r, movie)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	headers := make(http.Header)
 	headers.Set("Location", fmt.Sprintf("/v1/movies/%d", movie.ID))
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
 
 	err = app.writeJSON(w, http.StatusCreated, envelope{"movie": movie}, headers)
 	if err != nil {
@@ -95,7 +249,8 @@ func (app *application) createMovieHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// getMovieHandler handles requests for "GET /v1/movies/:id".
+// getMovieHandler handles requests for "GET /v1/movies/:id". The title and
+// synopsis are localized according to the Accept-Language header.
 func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -103,7 +258,16 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	v := validator.New()
+
+	view := app.readMovieView(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -114,13 +278,48 @@ func (app *application) getMovieHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.localizeMovies(r, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	movie.Collection, err = app.readMovieCollection(movie.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	headers := make(http.Header)
+	if len(view.Include) == 0 {
+		etag := app.variantETag(r, movieETag(movie), view)
+		if app.notModified(w, r, etag) {
+			return
+		}
+		headers.Set("ETag", etag)
+	}
+	if movie.Language != "" {
+		headers.Set("Content-Language", movie.Language)
+	}
+
+	representations, err := app.presentMovies(r, view, movie)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": representations[0]}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-// updateMovieHandler handles requests for "PUT /v1/movies".
+// updateMovieHandler handles requests for "PATCH /v1/movies/:id". The request
+// must include an If-Match header with the movie's current ETag, so that a
+// client can't overwrite changes it hasn't seen. Published movies edited by
+// someone who isn't a moderator go back to the moderation queue.
 func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
@@ -128,7 +327,7 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	movie, err := app.models.Movies.Get(id)
+	movie, err := app.readVisibleMovie(r, id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -139,19 +338,139 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var input struct {
-		Title   *string       `json:"title"`
-		Year    *int32        `json:"year"`
-		Runtime *data.Runtime `json:"runtime"`
-		Genres  []string      `json:"genres"`
+	if !app.moviePreconditionsMet(w, r, movie) {
+		return
 	}
 
-	err = app.readJSON(w, r, &input)
+	// Besides the plain JSON body with the fields to change, clients can send a
+	// JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902) document, which
+	// also allow clearing fields and editing individual genres.
+	switch app.mediaType(r) {
+	case "application/merge-patch+json":
+		err = app.readMoviePatch(w, r, movie, jsonpatch.MergePatch)
+	case "application/json-patch+json":
+		err = app.readMoviePatch(w, r, movie, jsonpatch.Apply)
+	default:
+		err = app.readMovieUpdate(w, r, movie)
+	}
 	if err != nil {
-		app.badRequestResponse(w, r, err)
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			app.patchTestFailedResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	genres, err := app.models.Genres.Catalogue()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovie(v, movie, genres); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	resubmitEditedMovie(movie, moderator)
+
+	user := app.contextGetUser(r)
+
+	// The movie's version matched the If-Match header above, so if the update
+	// now conflicts, someone else has changed the movie in the meantime and the
+	// client's precondition no longer holds.
+	err = app.models.Movies.Update(movie, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			app.externalIDConflictResponse(w, r, movie)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
+	headers := make(http.Header)
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// externalIDConflictResponse sends a 409 Conflict response listing the movies
+// that already hold any of the external identifiers of the given movie. Since
+// the identifiers are unique, the response usually names a single movie. Only
+// moderators are shown movies that aren't live, so for everyone else, the list
+// may be empty even though the conflict is real.
+func (app *application) externalIDConflictResponse(w http.ResponseWriter, r *http.Request, movie *data.Movie) {
+	moderator, err := app.canModerate(r)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	status := data.MovieStatusPublished
+	if moderator {
+		status = ""
+	}
+
+	candidates := []*data.Movie{}
+
+	for source, id := range movie.ExternalIDs {
+		criteria := data.MovieCriteria{ExternalIDs: data.ExternalIDs{source: id}, Status: status}
+		filters := data.Filters{Page: 1, PageSize: 1, Sort: "id", SortSafeValues: []string{"id"}}
+
+		movies, _, err := app.models.Movies.GetAll(criteria, filters)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		for _, candidate := range movies {
+			if candidate.ID != movie.ID {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	message := "Another movie already has one of these external identifiers."
+	app.duplicateMovieResponse(w, r, message, candidates)
+}
+
+// readMovieUpdate reads a plain JSON body and applies the fields present in it
+// to the movie, leaving the other fields unchanged.
+func (app *application) readMovieUpdate(w http.ResponseWriter, r *http.Request, movie *data.Movie) error {
+	var input struct {
+		Title       *string          `json:"title"`
+		Year        *int32           `json:"year"`
+		Runtime     *data.Runtime    `json:"runtime"`
+		Genres      []string         `json:"genres"`
+		ExternalIDs data.ExternalIDs `json:"external_ids"`
+		PublishAt   nullableTime     `json:"publish_at"`
+		UnpublishAt nullableTime     `json:"unpublish_at"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
 	if input.Title != nil {
 		movie.Title = *input.Title
 	}
@@ -164,40 +483,215 @@ func (app *application) updateMovieHandler(w http.ResponseWriter, r *http.Reques
 	if input.Genres != nil {
 		movie.Genres = input.Genres
 	}
+	mergeExternalIDs(movie, input.ExternalIDs)
+	if input.PublishAt.Set {
+		movie.PublishAt = input.PublishAt.Value
+	}
+	if input.UnpublishAt.Set {
+		movie.UnpublishAt = input.UnpublishAt.Value
+	}
 
-	v := validator.New()
+	return nil
+}
 
-	if data.ValidateMovie(v, movie); !v.Valid() {
-		app.failedValidationResponse(w, r, v.Errors)
+// nullableTime holds a timestamp of a partial update, telling apart a field
+// that is absent, which leaves the timestamp unchanged, from a null field,
+// which clears it.
+type nullableTime struct {
+	Set   bool
+	Value *time.Time
+}
+
+func (t *nullableTime) UnmarshalJSON(js []byte) error {
+	t.Set = true
+	return json.Unmarshal(js, &t.Value)
+}
+
+// mergeExternalIDs merges the given external identifiers into the movie's
+// existing ones. An empty identifier unlinks the movie from its source.
+func mergeExternalIDs(movie *data.Movie, ids data.ExternalIDs) {
+	for source, id := range ids {
+		if movie.ExternalIDs == nil {
+			movie.ExternalIDs = data.ExternalIDs{}
+		}
+		if id == "" {
+			delete(movie.ExternalIDs, source)
+		} else {
+			movie.ExternalIDs[source] = id
+		}
+	}
+}
+
+// movieDocument holds the fields of a movie that clients can edit. It is the
+// document that JSON patches are applied to.
+type movieDocument struct {
+	Title       string           `json:"title,omitempty"`
+	Year        int32            `json:"year,omitempty"`
+	Runtime     data.Runtime     `json:"runtime,omitempty"`
+	Genres      []string         `json:"genres,omitempty"`
+	ExternalIDs data.ExternalIDs `json:"external_ids,omitempty"`
+	PublishAt   *time.Time       `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time       `json:"unpublish_at,omitempty"`
+}
+
+// readMoviePatch reads a patch document from the request body, applies it to
+// the editable fields of the movie using the apply function, and copies the
+// result back to the movie. Fields removed by the patch are reset to their
+// zero value, and left for ValidateMovie() to reject.
+func (app *application) readMoviePatch(
+	w http.ResponseWriter,
+	r *http.Request,
+	movie *data.Movie,
+	apply func(doc, patch []byte) ([]byte, error),
+) error {
+	patch, err := app.readBody(w, r)
+	if err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(movieDocument{
+		Title:       movie.Title,
+		Year:        movie.Year,
+		Runtime:     movie.Runtime,
+		Genres:      movie.Genres,
+		ExternalIDs: movie.ExternalIDs,
+		PublishAt:   movie.PublishAt,
+		UnpublishAt: movie.UnpublishAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	patched, err := apply(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	var result movieDocument
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(&result)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &unmarshalTypeError):
+			return fmt.Errorf("patch results in incorrect JSON type for field %q", unmarshalTypeError.Field)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return fmt.Errorf("patch results in unknown key %s", fieldName)
+		case errors.Is(err, data.ErrInvalidRuntimeFormat):
+			return fmt.Errorf("patch results in invalid runtime: %w", err)
+		default:
+			return fmt.Errorf("patch results in an invalid document: %w", err)
+		}
+	}
+
+	movie.Title = result.Title
+	movie.Year = result.Year
+	movie.Runtime = result.Runtime
+	movie.Genres = result.Genres
+	movie.ExternalIDs = result.ExternalIDs
+	movie.PublishAt = result.PublishAt
+	movie.UnpublishAt = result.UnpublishAt
+
+	for source, id := range movie.ExternalIDs {
+		if id == "" {
+			delete(movie.ExternalIDs, source)
+		}
+	}
+
+	return nil
+}
+
+// deleteMovieHandler handles requests for "DELETE /v1/movies/:id". Like
+// updates, deletions require an If-Match header with the movie's current ETag.
+func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Movies.Update(movie)
+	movie, err := app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.moviePreconditionsMet(w, r, movie) {
+		return
+	}
+
+	err = app.models.Movies.Delete(movie.ID, movie.Version, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
-			app.editConflictResponse(w, r)
+			app.preconditionFailedResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, nil)
+	err = app.writeJSON(w, http.StatusCreated, envelope{"message": "movie successfully moved to trash"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-// deleteMovieHandler handles requests for "DELETE /v1/movies/:id".
-func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
+// getTrashedMoviesHandler handles requests for "GET /v1/movies/trash".
+func (app *application) getTrashedMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+
+	input.Filters.SortSafeValues = []string{
+		"id", "title", "deleted_at", "-id", "-title", "-deleted_at",
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, metadata, err := app.models.Movies.GetAllTrashed(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.formatRuntimes(r, movies...)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movies": movies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreMovieHandler handles requests for "POST /v1/movies/:id/restore".
+func (app *application) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.readIDParam(r)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
-	err = app.models.Movies.Delete(id)
+	err = app.models.Movies.Restore(id, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -208,7 +702,18 @@ func (app *application) deleteMovieHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"message": "movie successfully deleted"}, nil)
+	movie, err := app.models.Movies.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
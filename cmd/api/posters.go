@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"net/http"
+	"time"
+
+	// Register the GIF and PNG decoders with image.Decode; JPEG is registered
+	// by the image/jpeg import above.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/walkccc/greenlight/internal/data"
+)
+
+// posterFormats maps the content types that are accepted for posters, as
+// sniffed from the uploaded bytes, to the file extension they are stored with.
+var posterFormats = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// posterMaxPixels caps the decoded size of a poster, so that a small but
+// highly compressed file can't exhaust memory when it's decoded.
+const posterMaxPixels = 40_000_000
+
+// posterThumbnails holds the names and widths of the thumbnails generated for
+// every poster. Thumbnails are never wider than the poster itself.
+var posterThumbnails = []struct {
+	name  string
+	width int
+}{
+	{"small", 185},
+	{"medium", 342},
+	{"large", 780},
+}
+
+// updateMoviePosterHandler handles requests for "PUT /v1/movies/:id/poster".
+// The poster is read from the "poster" part of a multipart form, and replaces
+// the movie's existing poster along with its thumbnails.
+func (app *application) updateMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if app.mediaType(r) != "multipart/form-data" {
+		app.unsupportedMediaTypeResponse(w, r, "The poster must be uploaded as multipart/form-data.")
+		return
+	}
+
+	// Leave room for the multipart boundaries and headers around the image.
+	r.Body = http.MaxBytesReader(w, r.Body, app.config.posters.maxSize+64<<10)
+
+	part, err := app.readFormFile(r, "poster")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(part, app.config.posters.maxSize+1))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.payloadTooLargeResponse(w, r, app.config.posters.maxSize)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	if int64(len(content)) > app.config.posters.maxSize {
+		app.payloadTooLargeResponse(w, r, app.config.posters.maxSize)
+		return
+	}
+
+	// The content type declared by the client is ignored in favor of sniffing
+	// the bytes themselves.
+	contentType := http.DetectContentType(content)
+	ext, ok := posterFormats[contentType]
+	if !ok {
+		app.unsupportedMediaTypeResponse(w, r, "The poster must be a JPEG, PNG or GIF image.")
+		return
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("poster can't be decoded: %w", err))
+		return
+	}
+	if config.Width < 1 || config.Height < 1 {
+		app.badRequestResponse(w, r, errors.New("poster must not be empty"))
+		return
+	}
+	if config.Width*config.Height > posterMaxPixels {
+		app.badRequestResponse(w, r, fmt.Errorf("poster must not have more than %d pixels", posterMaxPixels))
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("poster can't be decoded: %w", err))
+		return
+	}
+
+	// Keys are derived from the content, so a new poster never reuses the URLs
+	// of the one it replaces, which may still be cached by clients.
+	sum := sha256.Sum256(content)
+	prefix := fmt.Sprintf("posters/%d/%s", movie.ID, hex.EncodeToString(sum[:])[:16])
+
+	poster := &data.Poster{
+		Image: data.Image{
+			Key:         prefix + "/original." + ext,
+			ContentType: contentType,
+			Width:       config.Width,
+			Height:      config.Height,
+			Size:        int64(len(content)),
+		},
+		Thumbnails: map[string]data.Image{},
+	}
+
+	blobs := map[string][]byte{poster.Key: content}
+
+	// Every thumbnail is scaled down from the same flattened copy, rather than
+	// converting the source again for each size.
+	flat := flattenImage(img)
+
+	for _, size := range posterThumbnails {
+		thumbnail := resizeImage(flat, min(size.width, config.Width))
+
+		var buf bytes.Buffer
+		err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: 85})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		key := prefix + "/" + size.name + ".jpg"
+		blobs[key] = buf.Bytes()
+		poster.Thumbnails[size.name] = data.Image{
+			Key:         key,
+			ContentType: "image/jpeg",
+			Width:       thumbnail.Bounds().Dx(),
+			Height:      thumbnail.Bounds().Dy(),
+			Size:        int64(buf.Len()),
+		}
+	}
+
+	err = app.storePosterImages(r.Context(), poster, blobs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	old, err := app.models.Movies.SetPoster(movie.ID, poster)
+	if err != nil {
+		if movie.Poster == nil || movie.Poster.Key != poster.Key {
+			app.deletePosterImages(poster)
+		}
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Uploading the same image twice yields the same keys, which must not be
+	// deleted as part of the old poster.
+	if old != nil && old.Key != poster.Key {
+		app.deletePosterImages(old)
+	}
+
+	movie.Poster = poster
+
+	headers := make(http.Header)
+	headers.Set("ETag", app.variantETag(r, movieETag(movie), movieView{}))
+
+	app.formatRuntimes(r, movie)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"movie": movie}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteMoviePosterHandler handles requests for
+// "DELETE /v1/movies/:id/poster".
+func (app *application) deleteMoviePosterHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	old, err := app.models.Movies.SetPoster(id, nil)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if old == nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.deletePosterImages(old)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "poster successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// storePosterImages puts the poster's images into the blob store and fills in
+// their URLs. If any of them fails, the ones already stored are deleted again.
+func (app *application) storePosterImages(ctx context.Context, poster *data.Poster, blobs map[string][]byte) error {
+	stored := []string{}
+
+	for key, content := range blobs {
+		contentType := "image/jpeg"
+		if key == poster.Key {
+			contentType = poster.ContentType
+		}
+
+		err := app.blobs.Put(ctx, key, bytes.NewReader(content), int64(len(content)), contentType)
+		if err != nil {
+			for _, key := range stored {
+				app.blobs.Delete(context.Background(), key)
+			}
+			return err
+		}
+		stored = append(stored, key)
+	}
+
+	poster.URL = app.blobs.URL(poster.Key)
+	for name, thumbnail := range poster.Thumbnails {
+		thumbnail.URL = app.blobs.URL(thumbnail.Key)
+		poster.Thumbnails[name] = thumbnail
+	}
+
+	return nil
+}
+
+// deletePosterImages deletes the poster's images from the blob store in the
+// background. Failures are only logged, as they leave nothing but unreferenced
+// files behind.
+func (app *application) deletePosterImages(poster *data.Poster) {
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		for _, key := range poster.Keys() {
+			err := app.blobs.Delete(ctx, key)
+			if err != nil {
+				app.logger.Error(err.Error(), "key", key)
+			}
+		}
+	})
+}
+
+// flattenImage converts img to RGBA, with its origin at (0, 0). Transparent
+// areas are flattened onto white, as thumbnails are stored as JPEG.
+func flattenImage(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+
+	return flat
+}
+
+// resizeImage scales src, as returned by flattenImage, down to the given
+// width, keeping its aspect ratio. Each pixel of the result is the average of
+// the block of source pixels it covers, which avoids the aliasing of
+// nearest-neighbor scaling.
+func resizeImage(src *image.RGBA, width int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	height := max(1, srcHeight*width/srcWidth)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					pix := src.Pix[offset : offset+4]
+					r += int(pix[0])
+					g += int(pix[1])
+					b += int(pix[2])
+					a += int(pix[3])
+					n++
+					offset += 4
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset+0] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// getMovieReleasesHandler handles requests for "GET /v1/movies/:id/releases".
+func (app *application) getMovieReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	releases, err := app.models.Releases.GetAllForMovie(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"releases": releases}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateMovieReleasesHandler handles requests for
+// "PUT /v1/movies/:id/releases". The body holds the movie's full list of
+// releases, one per country, which replaces the existing one. Country codes
+// are case-insensitive.
+func (app *application) updateMovieReleasesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Releases []struct {
+			Country       string `json:"country"`
+			Date          string `json:"date"`
+			Certification string `json:"certification"`
+		} `json:"releases"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var releases []*data.Release
+	if input.Releases != nil {
+		releases = []*data.Release{}
+	}
+	for _, release := range input.Releases {
+		releases = append(releases, &data.Release{
+			MovieID:       id,
+			Country:       strings.ToUpper(strings.TrimSpace(release.Country)),
+			Date:          release.Date,
+			Certification: strings.TrimSpace(release.Certification),
+		})
+	}
+
+	v := validator.New()
+
+	if data.ValidateReleases(v, releases); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Releases.SetForMovie(id, releases)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	releases, err = app.models.Releases.GetAllForMovie(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"releases": releases}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -28,7 +28,26 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(
 		http.MethodGet,
 		"/v1/movies/:id",
-		app.requirePermission("movies:read", app.getMovieHandler),
+		app.staticOrParam(
+			"id",
+			map[string]http.HandlerFunc{
+				"trash":  app.requirePermission("movies:write", app.getTrashedMoviesHandler),
+				"export": app.requirePermission("movies:read", app.exportMoviesHandler),
+			},
+			app.requirePermission("movies:read", app.getMovieHandler),
+		),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id",
+		app.staticOrParam(
+			"id",
+			map[string]http.HandlerFunc{
+				"import": app.requirePermission("movies:write", app.importMoviesHandler),
+				"batch":  app.requirePermission("movies:write", app.batchMoviesHandler),
+			},
+			app.methodNotAllowedResponse,
+		),
 	)
 	router.HandlerFunc(
 		http.MethodPatch,
@@ -40,6 +59,285 @@ func (app *application) routes() http.Handler {
 		"/v1/movies/:id",
 		app.requirePermission("movies:write", app.deleteMovieHandler),
 	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id/restore",
+		app.requirePermission("movies:write", app.restoreMovieHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/versions",
+		app.requirePermission("movies:read", app.getMovieVersionsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/versions/:version",
+		app.requirePermission("movies:read", app.getMovieVersionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/diff",
+		app.requirePermission("movies:read", app.getMovieDiffHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id/revert",
+		app.requirePermission("movies:write", app.revertMovieHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id/submit",
+		app.requirePermission("movies:write", app.submitMovieHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/related",
+		app.requirePermission("movies:read", app.getRelatedMoviesHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/tags",
+		app.requirePermission("movies:read", app.getMovieTagsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id/tags",
+		app.requirePermission("movies:write", app.addMovieTagsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/movies/:id/tags/:tag",
+		app.requirePermission("movies:write", app.removeMovieTagHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/translations",
+		app.requirePermission("movies:read", app.getMovieTranslationsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/movies/:id/translations/:language",
+		app.requirePermission("movies:write", app.updateMovieTranslationHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/movies/:id/translations/:language",
+		app.requirePermission("movies:write", app.deleteMovieTranslationHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/movies/:id/poster",
+		app.requirePermission("movies:write", app.updateMoviePosterHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/movies/:id/poster",
+		app.requirePermission("movies:write", app.deleteMoviePosterHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/credits",
+		app.requirePermission("movies:read", app.getMovieCreditsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/movies/:id/credits",
+		app.requirePermission("movies:write", app.updateMovieCreditsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/releases",
+		app.requirePermission("movies:read", app.getMovieReleasesHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/movies/:id/releases",
+		app.requirePermission("movies:write", app.updateMovieReleasesHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/movies/:id/reviews",
+		app.requirePermission("movies:read", app.getMovieReviewsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/movies/:id/reviews",
+		app.requirePermission("movies:read", app.createMovieReviewHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPut,
+		"/v1/movies/:id/reviews",
+		app.requirePermission("movies:read", app.updateMovieReviewHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/movies/:id/reviews",
+		app.requirePermission("movies:read", app.deleteMovieReviewHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/people",
+		app.requirePermission("movies:read", app.getPeopleHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/people",
+		app.requirePermission("movies:write", app.createPersonHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/people/:id",
+		app.requirePermission("movies:read", app.getPersonHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/people/:id",
+		app.requirePermission("movies:write", app.updatePersonHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/people/:id",
+		app.requirePermission("movies:write", app.deletePersonHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/lists",
+		app.requirePermission("movies:read", app.getListsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/lists",
+		app.requirePermission("movies:read", app.createListHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/lists/:id",
+		app.requirePermission("movies:read", app.getListHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/lists/:id",
+		app.requirePermission("movies:read", app.updateListHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/lists/:id",
+		app.requirePermission("movies:read", app.deleteListHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/lists/:id/entries",
+		app.requirePermission("movies:read", app.addListEntryHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/lists/:id/entries/:movie_id",
+		app.requirePermission("movies:read", app.updateListEntryHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/lists/:id/entries/:movie_id",
+		app.requirePermission("movies:read", app.removeListEntryHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/collections",
+		app.requirePermission("movies:read", app.getCollectionsHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/collections",
+		app.requirePermission("movies:write", app.createCollectionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/collections/:id",
+		app.requirePermission("movies:read", app.getCollectionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/collections/:id",
+		app.requirePermission("movies:write", app.updateCollectionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/collections/:id",
+		app.requirePermission("movies:write", app.deleteCollectionHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/collections/:id/movies",
+		app.requirePermission("movies:write", app.addCollectionEntryHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/collections/:id/movies/:movie_id",
+		app.requirePermission("movies:write", app.updateCollectionEntryHandler),
+	)
+	router.HandlerFunc(
+		http.MethodDelete,
+		"/v1/collections/:id/movies/:movie_id",
+		app.requirePermission("movies:write", app.removeCollectionEntryHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/genres",
+		app.requirePermission("movies:read", app.getGenresHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/genres",
+		app.requirePermission("genres:write", app.createGenreHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPatch,
+		"/v1/genres/:id",
+		app.requirePermission("genres:write", app.updateGenreHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/genres/:id/merge",
+		app.requirePermission("genres:write", app.mergeGenreHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/tags",
+		app.requirePermission("movies:read", app.getTagCloudHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/stats/movies",
+		app.requirePermission("movies:read", app.getMovieStatsHandler),
+	)
+
+	router.HandlerFunc(
+		http.MethodGet,
+		"/v1/moderation/movies",
+		app.requirePermission("movies:moderate", app.getModerationQueueHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/moderation/movies/:id/approve",
+		app.requirePermission("movies:moderate", app.approveMovieHandler),
+	)
+	router.HandlerFunc(
+		http.MethodPost,
+		"/v1/moderation/movies/:id/reject",
+		app.requirePermission("movies:moderate", app.rejectMovieHandler),
+	)
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.createUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
@@ -50,6 +348,16 @@ func (app *application) routes() http.Handler {
 		app.createAuthenticationTokenHandler,
 	)
 
+	// Serve the files of the local blob store, so that the URLs it hands out
+	// work without a separate file server.
+	if app.config.storage.backend == "local" {
+		router.Handler(
+			http.MethodGet,
+			"/uploads/*filepath",
+			http.StripPrefix("/uploads", http.FileServer(http.Dir(app.config.storage.dir))),
+		)
+	}
+
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
 	standard := alice.New(
@@ -58,6 +366,29 @@ func (app *application) routes() http.Handler {
 		app.enableCORS,
 		app.rateLimit,
 		app.authenticate,
+		app.negotiateRuntimeFormat,
 	)
 	return standard.Then(router)
 }
+
+// staticOrParam works around httprouter not allowing a static path segment
+// (e.g. "/v1/movies/trash") to share its position with a named parameter (e.g.
+// "/v1/movies/:id"). The route is registered once with the named parameter, and
+// if the parameter's value matches one of the keys in static, the matching
+// handler is called instead of next.
+func (app *application) staticOrParam(
+	param string,
+	static map[string]http.HandlerFunc,
+	next http.HandlerFunc,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := httprouter.ParamsFromContext(r.Context())
+
+		if handler, ok := static[params.ByName(param)]; ok {
+			handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
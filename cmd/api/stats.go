@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// statsCache holds recently computed movie statistics for a short while, as
+// computing them scans every matching movie.
+type statsCache struct {
+	ttl     time.Duration
+	mtx     sync.Mutex
+	entries map[string]statsCacheEntry
+}
+
+type statsCacheEntry struct {
+	stats   *data.MovieStats
+	expires time.Time
+}
+
+func newStatsCache(ttl time.Duration) *statsCache {
+	return &statsCache{ttl: ttl, entries: make(map[string]statsCacheEntry)}
+}
+
+// get returns the cached statistics for the key, unless they have expired.
+func (c *statsCache) get(key string) (*data.MovieStats, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.stats, true
+}
+
+// set caches the statistics for the key. Expired entries are removed at the
+// same time, so that the cache doesn't grow with every combination of criteria
+// ever requested.
+func (c *statsCache) set(key string, stats *data.MovieStats) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = statsCacheEntry{stats: stats, expires: now.Add(c.ttl)}
+}
+
+// statsCacheKey returns the cache key of the statistics for the criteria.
+// Genres and tags are sorted, as their order doesn't change which movies match.
+func statsCacheKey(criteria data.MovieCriteria) string {
+	genres := slices.Clone(criteria.Genres)
+	slices.Sort(genres)
+	tags := slices.Clone(criteria.Tags)
+	slices.Sort(tags)
+
+	// Maps are printed with their keys sorted, so equal identifiers always
+	// yield the same key.
+	return fmt.Sprintf(
+		"%q %q %d %v %d %q %q %q %t",
+		criteria.Title, genres, criteria.PersonID, criteria.ExternalIDs, criteria.CollectionID, tags, criteria.Status,
+		criteria.ReleasedIn, criteria.Upcoming,
+	)
+}
+
+// getMovieStatsHandler handles requests for "GET /v1/stats/movies". It accepts
+// the same criteria as "GET /v1/movies".
+func (app *application) getMovieStatsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	criteria := app.readMovieCriteria(r.URL.Query(), v)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.movieCriteriaPermitted(w, r, criteria) {
+		return
+	}
+
+	err := app.normalizeMovieCriteria(&criteria)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := statsCacheKey(criteria)
+
+	stats, ok := app.stats.get(key)
+	if !ok {
+		stats, err = app.models.Movies.GetStats(criteria)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.stats.set(key, stats)
+	}
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(app.stats.ttl.Seconds())))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"stats": stats}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/walkccc/greenlight/internal/data"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// readLanguages returns the languages listed in the Accept-Language header of
+// the request, most preferred first. Each language with a region is followed by
+// its primary language as a fallback, so that "fr-CA" falls back to "fr".
+// Wildcards, languages with a quality of 0 and malformed tags are left out.
+func (app *application) readLanguages(r *http.Request) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var ranges []weighted
+
+	for _, value := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(value, ";")
+		tag = data.CanonicalLanguage(tag)
+		if !validator.Matches(tag, data.LanguageRX) {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, weighted{tag, quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	languages := []string{}
+	for _, r := range ranges {
+		primary, _, _ := strings.Cut(r.tag, "-")
+		for _, language := range []string{r.tag, primary} {
+			if !slices.Contains(languages, language) {
+				languages = append(languages, language)
+			}
+		}
+	}
+
+	return languages
+}
+
+// localizeMovies localizes the movies into the most preferred language of the
+// request that they have a translation for. Movies without one are left in
+// their original language.
+func (app *application) localizeMovies(r *http.Request, movies ...*data.Movie) error {
+	languages := app.readLanguages(r)
+	if len(languages) == 0 || len(movies) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(movies))
+	for i, movie := range movies {
+		ids[i] = movie.ID
+	}
+
+	translations, err := app.models.Translations.GetForMovies(ids, languages)
+	if err != nil {
+		return err
+	}
+
+	translationsByMovie := make(map[int64][]*data.MovieTranslation)
+	for _, translation := range translations {
+		translationsByMovie[translation.MovieID] = append(translationsByMovie[translation.MovieID], translation)
+	}
+
+	for _, movie := range movies {
+		if translation := data.PickTranslation(translationsByMovie[movie.ID], languages); translation != nil {
+			movie.Localize(translation)
+		}
+	}
+
+	return nil
+}
+
+// readLanguageParam retrieves the "language" URL parameter from the current
+// request context in its canonical form.
+func (app *application) readLanguageParam(r *http.Request) string {
+	params := httprouter.ParamsFromContext(r.Context())
+	return data.CanonicalLanguage(params.ByName("language"))
+}
+
+// getMovieTranslationsHandler handles requests for
+// "GET /v1/movies/:id/translations".
+func (app *application) getMovieTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	translations, err := app.models.Translations.GetAllForMovie(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translations": translations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateMovieTranslationHandler handles requests for
+// "PUT /v1/movies/:id/translations/:language", which create or replace the
+// movie's title and synopsis in the language.
+func (app *application) updateMovieTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Title    string `json:"title"`
+		Synopsis string `json:"synopsis"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	translation := &data.MovieTranslation{
+		MovieID:  id,
+		Language: app.readLanguageParam(r),
+		Title:    input.Title,
+		Synopsis: input.Synopsis,
+	}
+
+	v := validator.New()
+
+	if data.ValidateMovieTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.Set(translation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteMovieTranslationHandler handles requests for
+// "DELETE /v1/movies/:id/translations/:language".
+func (app *application) deleteMovieTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.readVisibleMovie(r, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Translations.Delete(id, app.readLanguageParam(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
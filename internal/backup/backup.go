@@ -0,0 +1,282 @@
+// Package backup dumps the database into a compressed archive and restores it,
+// so that the data can be moved between environments without direct access to
+// pg_dump.
+//
+// An archive is a gzipped tarball holding a "manifest.json" file followed by
+// one "tables/<name>.ndjson" file per table, with one JSON object per row. The
+// manifest records the format and schema versions of the archive, and the
+// number of rows and the SHA-256 checksum of every table file.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// FormatVersion is the version of the archive format. It must be incremented
+// whenever a change to the format would break restoring older archives.
+const FormatVersion = 1
+
+// manifestName is the name of the manifest in an archive.
+const manifestName = "manifest.json"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrDatabaseNotEmpty  = errors.New("database not empty")
+)
+
+// table describes a table in an archive. Tables are listed in an order that
+// satisfies their foreign keys, so that they can be restored one after the
+// other.
+type table struct {
+	name string
+	// serial is set on tables whose "id" column is filled by a sequence, which
+	// must be moved past the restored ids.
+	serial bool
+	// seeded is set on tables that migrations fill with reference data, which
+	// the archive replaces.
+	seeded bool
+}
+
+// tables lists the tables that are backed up. "RelatedMovies" is left out, as
+// it is recomputed from the movies periodically.
+var tables = []table{
+	{name: "Users", serial: true},
+	{name: "Permissions", serial: true, seeded: true},
+	{name: "UsersPermissions"},
+	{name: "Tokens"},
+	{name: "Genres", serial: true},
+	{name: "Movies", serial: true},
+	{name: "MovieVersions"},
+	{name: "MovieTranslations"},
+	{name: "People", serial: true},
+	{name: "Credits"},
+	{name: "Reviews"},
+	{name: "Lists", serial: true},
+	{name: "ListEntries"},
+	{name: "Collections", serial: true},
+	{name: "CollectionEntries"},
+	{name: "Tags", serial: true},
+	{name: "MovieTags"},
+	{name: "Releases"},
+}
+
+// lookupTable returns the table with the given name.
+func lookupTable(name string) (table, bool) {
+	for _, t := range tables {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return table{}, false
+}
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	SchemaVersion uint      `json:"schema_version"`
+	AppVersion    string    `json:"app_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Tables        []Table   `json:"tables"`
+}
+
+// Table describes the file of a table in an archive.
+type Table struct {
+	Name   string `json:"name"`
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// tableFileName returns the name of the file of a table in an archive.
+func tableFileName(name string) string {
+	return "tables/" + name + ".ndjson"
+}
+
+// Create writes an archive of the database to w, recording appVersion in the
+// manifest. Every table is read from the same snapshot of the database, so the
+// archive is consistent even while the application keeps running. Since the
+// manifest comes first in the archive, the tables are spooled to temporary
+// files until their checksums are known.
+func Create(ctx context.Context, db *sql.DB, w io.Writer, appVersion string) (*Manifest, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	version, dirty, err := SchemaVersion(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("migration %d failed halfway and must be fixed by hand", version)
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		SchemaVersion: version,
+		AppVersion:    appVersion,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+		Tables:        []Table{},
+	}
+
+	files := []*os.File{}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	for _, t := range tables {
+		// Tables created by migrations newer than the database's schema don't
+		// exist yet.
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT TO_REGCLASS($1) IS NOT NULL`, quoteIdentifier(t.name)).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		f, err := os.CreateTemp("", "greenlight-backup-*")
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+
+		entry, err := dumpTable(ctx, tx, t.name, f)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", t.name, err)
+		}
+		manifest.Tables = append(manifest.Tables, entry)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	js, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeFile(tw, manifestName, int64(len(js)), manifest.CreatedAt, bytes.NewReader(js))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, f := range files {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeFile(tw, tableFileName(manifest.Tables[i].Name), info.Size(), manifest.CreatedAt, f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// dumpTable writes every row of the table to w as a line of JSON, and returns
+// the table's entry in the manifest.
+func dumpTable(ctx context.Context, tx *sql.Tx, name string, w io.Writer) (Table, error) {
+	// The rows are converted by PostgreSQL itself, so that restoring them with
+	// JSON_POPULATE_RECORDSET gives back the same values, whatever their types.
+	query := fmt.Sprintf(`SELECT ROW_TO_JSON(t) FROM %s t`, quoteIdentifier(name))
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return Table{}, err
+	}
+	defer rows.Close()
+
+	hash := sha256.New()
+	bw := bufio.NewWriter(io.MultiWriter(w, hash))
+
+	entry := Table{Name: name}
+
+	for rows.Next() {
+		var row []byte
+		err := rows.Scan(&row)
+		if err != nil {
+			return Table{}, err
+		}
+
+		_, err = bw.Write(append(row, '\n'))
+		if err != nil {
+			return Table{}, err
+		}
+		entry.Rows++
+	}
+	if err = rows.Err(); err != nil {
+		return Table{}, err
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return Table{}, err
+	}
+
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
+
+// writeFile adds a regular file to the archive.
+func writeFile(tw *tar.Writer, name string, size int64, modTime time.Time, r io.Reader) error {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o600,
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, r)
+	return err
+}
+
+// quoteIdentifier quotes a table name for use in a query. Names only ever come
+// from the tables list, so they never hold quotes themselves.
+func quoteIdentifier(name string) string {
+	return `"` + name + `"`
+}
@@ -0,0 +1,276 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func NewMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.Nil(t, err)
+	return db, mock
+}
+
+// writeArchive returns an archive holding the manifest and the given table
+// files, in order.
+func writeArchive(t *testing.T, manifest *Manifest, files ...string) *bytes.Buffer {
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+
+	js, err := json.Marshal(manifest)
+	assert.Nil(t, err)
+	assert.Nil(t, writeFile(tw, manifestName, int64(len(js)), time.Now(), bytes.NewReader(js)))
+
+	for i, file := range files {
+		name := tableFileName(manifest.Tables[i].Name)
+		assert.Nil(t, writeFile(tw, name, int64(len(file)), time.Now(), bytes.NewBufferString(file)))
+	}
+
+	assert.Nil(t, tw.Close())
+	assert.Nil(t, zw.Close())
+	return &buf
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+var migrationsFS = fstest.MapFS{
+	"000001_create_users_table.up.sql":   {Data: []byte(`CREATE TABLE "Users" ();`)},
+	"000001_create_users_table.down.sql": {Data: []byte(`DROP TABLE "Users";`)},
+}
+
+func TestReadMigrations(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000010_create_lists_tables.up.sql":   {},
+			"000002_add_movies_check.up.sql":      {},
+			"000002_add_movies_check.down.sql":    {},
+			"000001_create_movies_table.up.sql":   {},
+			"000001_create_movies_table.down.sql": {},
+		}
+
+		migrations, err := readMigrations(fsys)
+		assert.Nil(t, err)
+		assert.Equal(t, []migration{
+			{version: 1, name: "000001_create_movies_table.up.sql"},
+			{version: 2, name: "000002_add_movies_check.up.sql"},
+			{version: 10, name: "000010_create_lists_tables.up.sql"},
+		}, migrations)
+	})
+
+	t.Run("DuplicateVersion", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"000001_create_movies_table.up.sql": {},
+			"000001_create_users_table.up.sql":  {},
+		}
+
+		_, err := readMigrations(fsys)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("InvalidVersion", func(t *testing.T) {
+		fsys := fstest.MapFS{"create_movies_table.up.sql": {}}
+
+		_, err := readMigrations(fsys)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	db, mock := NewMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT TO_REGCLASS\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, dirty := range []bool{true, false} {
+		if !dirty {
+			mock.ExpectExec(`CREATE TABLE "Users" \(\);`).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec(`TRUNCATE schema_migrations`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO schema_migrations \(version, dirty\) VALUES \(\$1, \$2\)`).
+			WithArgs(1, dirty).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	assert.Nil(t, Migrate(context.Background(), db, migrationsFS, 1))
+	assert.Nil(t, mock.ExpectationsWereMet())
+
+	err := Migrate(context.Background(), db, migrationsFS, 2)
+	assert.EqualError(t, err, "no migration for schema version 2")
+}
+
+func TestCreate(t *testing.T) {
+	db, mock := NewMock(t)
+	defer db.Close()
+
+	rows := `{"id":1,"name":"Alice"}` + "\n" + `{"id":2,"name":"Bob"}` + "\n"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT TO_REGCLASS\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(1, false))
+	for _, table := range tables {
+		// Only the users table exists at schema version 1.
+		mock.ExpectQuery(`SELECT TO_REGCLASS\(\$1\) IS NOT NULL`).
+			WithArgs(quoteIdentifier(table.name)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(table.name == "Users"))
+		if table.name == "Users" {
+			mock.ExpectQuery(`SELECT ROW_TO_JSON\(t\) FROM "Users" t`).
+				WillReturnRows(sqlmock.NewRows([]string{"row_to_json"}).
+					AddRow([]byte(`{"id":1,"name":"Alice"}`)).
+					AddRow([]byte(`{"id":2,"name":"Bob"}`)))
+		}
+	}
+	mock.ExpectCommit()
+
+	var buf bytes.Buffer
+
+	manifest, err := Create(context.Background(), db, &buf, "v1.0.0")
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, uint(1), manifest.SchemaVersion)
+	assert.Equal(t, []Table{{Name: "Users", Rows: 2, SHA256: sha256Hex(rows)}}, manifest.Tables)
+
+	zr, err := gzip.NewReader(&buf)
+	assert.Nil(t, err)
+	tr := tar.NewReader(zr)
+
+	read, err := readManifest(tr)
+	assert.Nil(t, err)
+	assert.Equal(t, manifest, read)
+
+	hdr, err := tr.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "tables/Users.ndjson", hdr.Name)
+
+	var file bytes.Buffer
+	_, err = file.ReadFrom(tr)
+	assert.Nil(t, err)
+	assert.Equal(t, rows, file.String())
+}
+
+func TestRestore(t *testing.T) {
+	rows := `{"id":1,"name":"Alice"}` + "\n" + `{"id":2,"name":"Bob"}` + "\n"
+
+	expectEmpty := func(mock sqlmock.Sqlmock, empty bool) {
+		mock.ExpectQuery(`SELECT TO_REGCLASS\(\$1\) IS NOT NULL`).
+			WithArgs(`"Users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM "Users"\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(!empty))
+	}
+
+	expectMigrated := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT TO_REGCLASS\('schema_migrations'\) IS NOT NULL`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations LIMIT 1`).
+			WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(1, false))
+		mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := NewMock(t)
+		defer db.Close()
+
+		manifest := &Manifest{
+			FormatVersion: FormatVersion,
+			SchemaVersion: 1,
+			Tables:        []Table{{Name: "Users", Rows: 2, SHA256: sha256Hex(rows)}},
+		}
+
+		expectEmpty(mock, true)
+		expectMigrated(mock)
+		mock.ExpectExec(`
+			INSERT INTO "Users"
+			SELECT \* FROM JSON_POPULATE_RECORDSET\(NULL::"Users", \$1\)`).
+			WithArgs(`[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}]`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(`
+			SELECT SETVAL\(PG_GET_SERIAL_SEQUENCE\(\$1, 'id'\), COALESCE\(MAX\(id\), 0\) \+ 1, false\)
+			FROM "Users"`).
+			WithArgs(`"Users"`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := Restore(context.Background(), db, writeArchive(t, manifest, rows), migrationsFS)
+		assert.Nil(t, err)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ErrDatabaseNotEmpty", func(t *testing.T) {
+		db, mock := NewMock(t)
+		defer db.Close()
+
+		manifest := &Manifest{
+			FormatVersion: FormatVersion,
+			SchemaVersion: 1,
+			Tables:        []Table{{Name: "Users", Rows: 2, SHA256: sha256Hex(rows)}},
+		}
+
+		// The database is left untouched: it isn't even migrated.
+		expectEmpty(mock, false)
+
+		_, err := Restore(context.Background(), db, writeArchive(t, manifest, rows), migrationsFS)
+		assert.ErrorIs(t, err, ErrDatabaseNotEmpty)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ErrChecksumMismatch", func(t *testing.T) {
+		db, mock := NewMock(t)
+		defer db.Close()
+
+		manifest := &Manifest{
+			FormatVersion: FormatVersion,
+			SchemaVersion: 1,
+			Tables:        []Table{{Name: "Users", Rows: 2, SHA256: sha256Hex(rows)}},
+		}
+		tampered := `{"id":1,"name":"Mallory"}` + "\n" + `{"id":2,"name":"Bob"}` + "\n"
+
+		expectEmpty(mock, true)
+		expectMigrated(mock)
+		mock.ExpectExec(`INSERT INTO "Users"`).
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectRollback()
+
+		_, err := Restore(context.Background(), db, writeArchive(t, manifest, tampered), migrationsFS)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("ErrUnsupportedFormat", func(t *testing.T) {
+		db, mock := NewMock(t)
+		defer db.Close()
+
+		manifest := &Manifest{FormatVersion: FormatVersion + 1, Tables: []Table{}}
+
+		_, err := Restore(context.Background(), db, writeArchive(t, manifest), migrationsFS)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+		assert.Nil(t, mock.ExpectationsWereMet())
+	})
+}
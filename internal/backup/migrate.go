@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// migration is an up migration, read from a file named like
+// "000001_create_movies_table.up.sql".
+type migration struct {
+	version uint
+	name    string
+}
+
+// readMigrations returns the up migrations in fsys, in the order they must be
+// applied.
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(names))
+
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q has no version", name)
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration %q has an invalid version", name)
+		}
+
+		migrations = append(migrations, migration{version: uint(version), name: name})
+	}
+
+	slices.SortFunc(migrations, func(a, b migration) int {
+		return cmp.Compare(a.version, b.version)
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version", migrations[i-1].name, migrations[i].name)
+		}
+	}
+
+	return migrations, nil
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// SchemaVersion returns the version of the last migration applied to the
+// database, which is 0 if none was, and whether that migration failed halfway.
+// Versions are read from the "schema_migrations" table maintained by the
+// migrate CLI.
+func SchemaVersion(ctx context.Context, q querier) (uint, bool, error) {
+	var exists bool
+
+	err := q.QueryRowContext(ctx, `SELECT TO_REGCLASS('schema_migrations') IS NOT NULL`).Scan(&exists)
+	if err != nil || !exists {
+		return 0, false, err
+	}
+
+	var version uint
+	var dirty bool
+
+	err = q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, false, nil
+		default:
+			return 0, false, err
+		}
+	}
+
+	return version, dirty, nil
+}
+
+// Migrate applies the up migrations in fsys to the database, up to and
+// including the target version. Progress is recorded the same way as the
+// migrate CLI does, so that either can carry on where the other stopped.
+func Migrate(ctx context.Context, db *sql.DB, fsys fs.FS, target uint) error {
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 || migrations[len(migrations)-1].version < target {
+		return fmt.Errorf("no migration for schema version %d", target)
+	}
+
+	current, dirty, err := SchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case dirty:
+		return fmt.Errorf("migration %d failed halfway and must be fixed by hand", current)
+	case current > target:
+		return fmt.Errorf("database schema version %d is newer than %d", current, target)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+
+		script, err := fs.ReadFile(fsys, m.name)
+		if err != nil {
+			return err
+		}
+
+		err = setSchemaVersion(ctx, db, m.version, true)
+		if err != nil {
+			return err
+		}
+
+		// Without arguments, the whole script runs as a single simple query,
+		// however many statements it holds.
+		_, err = db.ExecContext(ctx, string(script))
+		if err != nil {
+			return fmt.Errorf("migration %q: %w", m.name, err)
+		}
+
+		err = setSchemaVersion(ctx, db, m.version, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setSchemaVersion replaces the version recorded in the "schema_migrations"
+// table.
+func setSchemaVersion(ctx context.Context, db *sql.DB, version uint, dirty bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `TRUNCATE schema_migrations`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,254 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// batchSize is the number of rows inserted by each query during a restore.
+const batchSize = 1000
+
+// readManifest reads the manifest at the start of an archive and checks that
+// it can be restored.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+	if hdr.Name != manifestName {
+		return nil, fmt.Errorf("%w: archive starts with %q instead of the manifest", ErrUnsupportedFormat, hdr.Name)
+	}
+
+	var manifest Manifest
+
+	err = json.NewDecoder(tr).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d", ErrUnsupportedFormat, manifest.FormatVersion)
+	}
+
+	for _, entry := range manifest.Tables {
+		if _, ok := lookupTable(entry.Name); !ok {
+			return nil, fmt.Errorf("%w: unknown table %q", ErrUnsupportedFormat, entry.Name)
+		}
+	}
+
+	return &manifest, nil
+}
+
+// Restore loads an archive read from r into the database, which must not hold
+// any data yet. That is checked before anything else, so that a database
+// holding data is left untouched. The migrations in fsys are then applied up
+// to the schema version of the archive, and the rows are inserted in a single
+// transaction, which is rolled back unless every table file matches its
+// checksum in the manifest.
+func Restore(ctx context.Context, db *sql.DB, r io.Reader, fsys fs.FS) (*Manifest, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedFormat, err)
+	}
+	defer zr.Close()
+
+	tr := tar.NewReader(zr)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkEmpty(ctx, db, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Migrate(ctx, db, fsys, manifest.SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = clearSeededTables(ctx, tx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range manifest.Tables {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%w: archive is missing table %q", ErrUnsupportedFormat, entry.Name)
+			}
+			return nil, err
+		}
+		if hdr.Name != tableFileName(entry.Name) {
+			return nil, fmt.Errorf("%w: expected %q, found %q", ErrUnsupportedFormat, tableFileName(entry.Name), hdr.Name)
+		}
+
+		err = loadTable(ctx, tx, entry, tr)
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", entry.Name, err)
+		}
+	}
+
+	err = resetSequences(ctx, tx, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// checkEmpty checks that the tables in the manifest which already exist are
+// empty, apart from the reference data seeded by migrations. Tables that
+// don't exist yet are created empty by the migrations.
+func checkEmpty(ctx context.Context, db *sql.DB, manifest *Manifest) error {
+	for _, entry := range manifest.Tables {
+		t, _ := lookupTable(entry.Name)
+		if t.seeded {
+			continue
+		}
+
+		var exists bool
+
+		err := db.QueryRowContext(ctx, `SELECT TO_REGCLASS($1) IS NOT NULL`, quoteIdentifier(t.name)).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, quoteIdentifier(t.name))
+
+		err = db.QueryRowContext(ctx, query).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: table %q holds rows", ErrDatabaseNotEmpty, t.name)
+		}
+	}
+
+	return nil
+}
+
+// clearSeededTables deletes the reference data seeded by migrations, to make
+// room for the archived one.
+func clearSeededTables(ctx context.Context, tx *sql.Tx, manifest *Manifest) error {
+	for _, entry := range manifest.Tables {
+		t, _ := lookupTable(entry.Name)
+		if !t.seeded {
+			continue
+		}
+
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s`, quoteIdentifier(t.name)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTable inserts the rows of a table file, and checks them against the
+// table's entry in the manifest.
+func loadTable(ctx context.Context, tx *sql.Tx, entry Table, r io.Reader) error {
+	hash := sha256.New()
+	br := bufio.NewReader(io.TeeReader(r, hash))
+
+	var rows int64
+	var batch [][]byte
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+			batch = append(batch, line)
+			rows++
+		}
+
+		if len(batch) == batchSize || (errors.Is(err, io.EOF) && len(batch) > 0) {
+			insertErr := insertRows(ctx, tx, entry.Name, batch)
+			if insertErr != nil {
+				return insertErr
+			}
+			batch = batch[:0]
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != entry.SHA256 {
+		return fmt.Errorf("%w: expected sha256 %s, got %s", ErrChecksumMismatch, entry.SHA256, sum)
+	}
+	if rows != entry.Rows {
+		return fmt.Errorf("%w: expected %d rows, got %d", ErrChecksumMismatch, entry.Rows, rows)
+	}
+
+	return nil
+}
+
+// insertRows inserts rows, each a JSON object as written by dumpTable, into
+// the table.
+func insertRows(ctx context.Context, tx *sql.Tx, name string, rows [][]byte) error {
+	js := append([]byte{'['}, bytes.Join(rows, []byte{','})...)
+	js = append(js, ']')
+
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s
+		SELECT * FROM JSON_POPULATE_RECORDSET(NULL::%[1]s, $1)`, quoteIdentifier(name))
+
+	_, err := tx.ExecContext(ctx, query, string(js))
+	return err
+}
+
+// resetSequences moves the sequences of the restored tables past their
+// highest id, so that new rows don't collide with the restored ones.
+func resetSequences(ctx context.Context, tx *sql.Tx, manifest *Manifest) error {
+	for _, entry := range manifest.Tables {
+		t, _ := lookupTable(entry.Name)
+		if !t.serial {
+			continue
+		}
+
+		query := fmt.Sprintf(`
+			SELECT SETVAL(PG_GET_SERIAL_SEQUENCE($1, 'id'), COALESCE(MAX(id), 0) + 1, false)
+			FROM %s`, quoteIdentifier(t.name))
+
+		_, err := tx.ExecContext(ctx, query, quoteIdentifier(t.name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
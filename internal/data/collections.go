@@ -0,0 +1,520 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var ErrDuplicateCollectionEntry = errors.New("duplicate collection entry")
+
+// Collection holds an ordered group of movies that belong together, such as a
+// trilogy or a franchise.
+type Collection struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"-"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Version     int32     `json:"version"`
+}
+
+// CollectionEntry holds a movie of a collection. Positions start at 1 and have
+// no gaps. A movie belongs to at most one collection.
+type CollectionEntry struct {
+	CollectionID int64  `json:"-"`
+	Movie        *Movie `json:"movie"`
+	Position     int32  `json:"position"`
+}
+
+// CollectionSummary describes the collection that a movie belongs to, and
+// where the movie stands in it.
+type CollectionSummary struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Version  int32  `json:"-"`
+	Position int32  `json:"position"`
+	Size     int32  `json:"size"`
+}
+
+func ValidateCollection(v *validator.Validator, collection *Collection) {
+	v.Check(collection.Title != "", "title", "must be provided")
+	v.Check(len(collection.Title) <= 500, "title", "must not be more than 500 bytes long")
+	v.Check(len(collection.Description) <= 10_000, "description", "must not be more than 10000 bytes long")
+}
+
+func ValidateCollectionEntry(v *validator.Validator, entry *CollectionEntry) {
+	v.Check(entry.Position >= 0, "position", "must not be negative")
+}
+
+type CollectionModelInterface interface {
+	Create(collection *Collection) error
+	Get(id int64) (*Collection, error)
+	GetAll(title string, filters Filters) ([]*Collection, Metadata, error)
+	Update(collection *Collection) error
+	Delete(id int64) error
+	GetEntries(collectionID int64) ([]*CollectionEntry, error)
+	GetSummary(movieID int64) (*CollectionSummary, error)
+	AddEntry(entry *CollectionEntry) error
+	MoveEntry(entry *CollectionEntry) error
+	RemoveEntry(collectionID, movieID int64) error
+}
+
+type CollectionModel struct {
+	DB *sql.DB
+}
+
+func (m CollectionModel) Create(collection *Collection) error {
+	query := `
+		INSERT INTO "Collections" (title, description)
+		VALUES ($1, $2)
+		RETURNING id, created_at, version`
+	args := []any{collection.Title, collection.Description}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.ID, &collection.CreatedAt, &collection.Version)
+}
+
+func (m CollectionModel) Get(id int64) (*Collection, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, title, description, version
+		FROM "Collections"
+		WHERE id = $1`
+
+	var collection Collection
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&collection.ID,
+		&collection.CreatedAt,
+		&collection.Title,
+		&collection.Description,
+		&collection.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &collection, nil
+}
+
+// GetAll returns a page of the collections whose title matches the given
+// full-text search, or of every collection if title is empty.
+func (m CollectionModel) GetAll(title string, filters Filters) ([]*Collection, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, created_at, title, description, version
+		FROM "Collections"
+		WHERE (TO_TSVECTOR('simple', title) @@ PLAINTO_TSQUERY('simple', $1) OR $1 = '')
+		ORDER BY %s, id ASC
+		LIMIT $2 OFFSET $3`, filters.orderBy(""))
+	args := []any{title, filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecord := 0
+	collections := []*Collection{}
+
+	for rows.Next() {
+		var collection Collection
+		err := rows.Scan(
+			&totalRecord,
+			&collection.ID,
+			&collection.CreatedAt,
+			&collection.Title,
+			&collection.Description,
+			&collection.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		collections = append(collections, &collection)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecord, filters.Page, filters.PageSize)
+	return collections, metadata, nil
+}
+
+func (m CollectionModel) Update(collection *Collection) error {
+	query := `
+		UPDATE "Collections"
+		SET title = $1, description = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version`
+	args := []any{
+		collection.Title,
+		collection.Description,
+		collection.ID,
+		collection.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&collection.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes a collection. Its movies are kept, they just no longer belong
+// to a collection.
+func (m CollectionModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM "Collections"
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetEntries returns all the entries of a collection in order. Collections are
+// small, so they aren't paginated. Movies in the trash or unpublished are left
+// out, and the positions are numbered among the remaining entries, the same way
+// as in GetSummary.
+func (m CollectionModel) GetEntries(collectionID int64) ([]*CollectionEntry, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			e.collection_id, ROW_NUMBER() OVER (ORDER BY e.position),
+			m.id, m.title, m.year, m.runtime, m.genres, m.version, m.rating_average, m.rating_count,
+			m.poster, m.external_ids
+		FROM "CollectionEntries" e
+		INNER JOIN "Movies" m ON m.id = e.movie_id
+		WHERE e.collection_id = $1 AND m.deleted_at IS NULL AND %s
+		ORDER BY e.position ASC`, publishedMovieSQL("m"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, collectionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*CollectionEntry{}
+
+	for rows.Next() {
+		entry := CollectionEntry{Movie: &Movie{}}
+		err := rows.Scan(
+			&entry.CollectionID,
+			&entry.Position,
+			&entry.Movie.ID,
+			&entry.Movie.Title,
+			&entry.Movie.Year,
+			&entry.Movie.Runtime,
+			pq.Array(&entry.Movie.Genres),
+			&entry.Movie.Version,
+			&entry.Movie.RatingAverage,
+			&entry.Movie.RatingCount,
+			&entry.Movie.Poster,
+			&entry.Movie.ExternalIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetSummary returns the summary of the collection that a movie belongs to, or
+// ErrRecordNotFound if it doesn't belong to any. Like in GetEntries, the other
+// movies of the collection only count towards the position and the size when
+// they are published and not in the trash.
+func (m CollectionModel) GetSummary(movieID int64) (*CollectionSummary, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			c.id, c.title, c.version,
+			(
+				SELECT COUNT(*) + 1
+				FROM "CollectionEntries" o
+				INNER JOIN "Movies" m ON m.id = o.movie_id
+				WHERE o.collection_id = c.id AND o.position < e.position AND m.deleted_at IS NULL AND %[1]s
+			),
+			(
+				SELECT COUNT(*) + 1
+				FROM "CollectionEntries" o
+				INNER JOIN "Movies" m ON m.id = o.movie_id
+				WHERE o.collection_id = c.id AND o.movie_id <> e.movie_id AND m.deleted_at IS NULL AND %[1]s
+			)
+		FROM "CollectionEntries" e
+		INNER JOIN "Collections" c ON c.id = e.collection_id
+		WHERE e.movie_id = $1`, publishedMovieSQL("m"))
+
+	var summary CollectionSummary
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID).Scan(
+		&summary.ID,
+		&summary.Title,
+		&summary.Version,
+		&summary.Position,
+		&summary.Size,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &summary, nil
+}
+
+// AddEntry adds a movie to a collection at the entry's position, moving the
+// entries from that position onwards down by one. A position of 0, or one past
+// the end of the collection, appends the movie.
+func (m CollectionModel) AddEntry(entry *CollectionEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	size, err := lockCollection(ctx, tx, entry.CollectionID)
+	if err != nil {
+		return err
+	}
+
+	if entry.Position < 1 || entry.Position > size+1 {
+		entry.Position = size + 1
+	}
+
+	query := `
+		UPDATE "CollectionEntries"
+		SET position = position + 1
+		WHERE collection_id = $1 AND position >= $2`
+
+	_, err = tx.ExecContext(ctx, query, entry.CollectionID, entry.Position)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO "CollectionEntries" (collection_id, movie_id, position)
+		VALUES ($1, $2, $3)`
+
+	_, err = tx.ExecContext(ctx, query, entry.CollectionID, entry.Movie.ID, entry.Position)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "CollectionEntries_pkey"`:
+			return ErrDuplicateCollectionEntry
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// MoveEntry moves an entry to its position, shifting the entries in between.
+// Positions past the end of the collection move the entry to the end.
+func (m CollectionModel) MoveEntry(entry *CollectionEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	size, err := lockCollection(ctx, tx, entry.CollectionID)
+	if err != nil {
+		return err
+	}
+
+	var oldPosition int32
+
+	query := `
+		SELECT position
+		FROM "CollectionEntries"
+		WHERE collection_id = $1 AND movie_id = $2`
+
+	err = tx.QueryRowContext(ctx, query, entry.CollectionID, entry.Movie.ID).Scan(&oldPosition)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if entry.Position < 1 || entry.Position > size {
+		entry.Position = size
+	}
+
+	// Close the gap left at the old position and open one at the new position.
+	query = `
+		UPDATE "CollectionEntries"
+		SET position = position
+			- CASE WHEN position > $2 THEN 1 ELSE 0 END
+			+ CASE WHEN position - CASE WHEN position > $2 THEN 1 ELSE 0 END >= $3 THEN 1 ELSE 0 END
+		WHERE collection_id = $1 AND movie_id <> $4`
+	args := []any{entry.CollectionID, oldPosition, entry.Position, entry.Movie.ID}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE "CollectionEntries"
+		SET position = $1
+		WHERE movie_id = $2`
+
+	_, err = tx.ExecContext(ctx, query, entry.Position, entry.Movie.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveEntry removes a movie from a collection, moving the entries after it
+// up by one.
+func (m CollectionModel) RemoveEntry(collectionID, movieID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = lockCollection(ctx, tx, collectionID)
+	if err != nil {
+		return err
+	}
+
+	var position int32
+
+	query := `
+		DELETE FROM "CollectionEntries"
+		WHERE collection_id = $1 AND movie_id = $2
+		RETURNING position`
+
+	err = tx.QueryRowContext(ctx, query, collectionID, movieID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `
+		UPDATE "CollectionEntries"
+		SET position = position - 1
+		WHERE collection_id = $1 AND position > $2`
+
+	_, err = tx.ExecContext(ctx, query, collectionID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockCollection locks a collection for the rest of the transaction, so that
+// concurrent changes to its entries can't give two of them the same position,
+// and returns the last position in use. Movies purged from the trash take
+// their entries with them and leave gaps, so the last position can exceed the
+// number of entries.
+func lockCollection(ctx context.Context, tx *sql.Tx, collectionID int64) (int32, error) {
+	query := `
+		SELECT id
+		FROM "Collections"
+		WHERE id = $1
+		FOR UPDATE`
+
+	var id int64
+
+	err := tx.QueryRowContext(ctx, query, collectionID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	query = `
+		SELECT COALESCE(MAX(position), 0)
+		FROM "CollectionEntries"
+		WHERE collection_id = $1`
+
+	var size int32
+
+	err = tx.QueryRowContext(ctx, query, collectionID).Scan(&size)
+	return size, err
+}
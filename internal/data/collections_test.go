@@ -0,0 +1,170 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateCollection(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection *Collection
+		errors     map[string]string
+	}{
+		{
+			name:       "Valid",
+			collection: &Collection{Title: "The Lord of the Rings", Description: "A trilogy."},
+			errors:     map[string]string{},
+		},
+		{
+			name:       "MissingTitle",
+			collection: &Collection{},
+			errors:     map[string]string{"title": "must be provided"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCollection(v, test.collection)
+			assert.Equal(t, test.errors, v.Errors)
+		})
+	}
+}
+
+func TestCollectionModel_AddEntry(t *testing.T) {
+	lockQuery := `
+		SELECT id
+		FROM "Collections"
+		WHERE id = \$1
+		FOR UPDATE`
+	countQuery := `
+		SELECT COALESCE\(MAX\(position\), 0\)
+		FROM "CollectionEntries"
+		WHERE collection_id = \$1`
+	shiftQuery := `
+		UPDATE "CollectionEntries"
+		SET position = position \+ 1
+		WHERE collection_id = \$1 AND position >= \$2`
+	insertQuery := `
+		INSERT INTO "CollectionEntries" \(collection_id, movie_id, position\)
+		VALUES \(\$1, \$2, \$3\)`
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model CollectionModel)
+	}{
+		{
+			name: "Append",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec(shiftQuery).WithArgs(3, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertQuery).WithArgs(3, 1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model CollectionModel) {
+				entry := &CollectionEntry{CollectionID: 3, Movie: &Movie{ID: 1}}
+				err := model.AddEntry(entry)
+				assert.Nil(t, err)
+				assert.Equal(t, int32(3), entry.Position)
+			},
+		},
+		{
+			name: "ErrDuplicateCollectionEntry",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec(shiftQuery).WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(insertQuery).
+					WithArgs(3, 1, 1).
+					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "CollectionEntries_pkey"`))
+				mock.ExpectRollback()
+			},
+			checkModel: func(model CollectionModel) {
+				err := model.AddEntry(&CollectionEntry{CollectionID: 3, Movie: &Movie{ID: 1}, Position: 1})
+				assert.Equal(t, ErrDuplicateCollectionEntry, err)
+			},
+		},
+		{
+			name: "ErrRecordNotFound",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			checkModel: func(model CollectionModel) {
+				err := model.AddEntry(&CollectionEntry{CollectionID: 3, Movie: &Movie{ID: 1}})
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := CollectionModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCollectionModel_GetSummary(t *testing.T) {
+	// Only the published movies that aren't in the trash count, as in GetEntries.
+	query := `
+		SELECT
+			c.id, c.title, c.version,
+			\(
+				SELECT COUNT\(\*\) \+ 1
+				FROM "CollectionEntries" o
+				INNER JOIN "Movies" m ON m.id = o.movie_id
+				WHERE o.collection_id = c.id AND o.position < e.position AND m.deleted_at IS NULL AND m.status = 'published' .+
+			\),
+			\(
+				SELECT COUNT\(\*\) \+ 1
+				FROM "CollectionEntries" o
+				INNER JOIN "Movies" m ON m.id = o.movie_id
+				WHERE o.collection_id = c.id AND o.movie_id <> e.movie_id AND m.deleted_at IS NULL AND m.status = 'published' .+
+			\)
+		FROM "CollectionEntries" e
+		INNER JOIN "Collections" c ON c.id = e.collection_id
+		WHERE e.movie_id = \$1`
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := CollectionModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectQuery(query).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "version", "position", "count"}).
+				AddRow(2, "Toy Story", 1, 1, 4))
+
+		summary, err := model.GetSummary(1)
+		assert.Nil(t, err)
+		assert.Equal(t, &CollectionSummary{ID: 2, Title: "Toy Story", Version: 1, Position: 1, Size: 4}, summary)
+	})
+
+	t.Run("ErrRecordNotFound", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := CollectionModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+
+		summary, err := model.GetSummary(1)
+		assert.Nil(t, summary)
+		assert.Equal(t, ErrRecordNotFound, err)
+	})
+}
@@ -0,0 +1,257 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var (
+	ErrUnknownPerson = errors.New("unknown person")
+)
+
+// Constants for the roles a person can be credited with.
+const (
+	RoleDirector = "director"
+	RoleActor    = "actor"
+	RoleWriter   = "writer"
+)
+
+// Credit links a person to a movie in a given role. Character is only set for
+// actors. Credits are listed in billing order, which is the order they were
+// given in when the movie's credits were last set. MovieTitle and PersonName
+// are filled in when listing credits, for convenience.
+type Credit struct {
+	MovieID    int64  `json:"movie_id"`
+	MovieTitle string `json:"movie_title,omitempty"`
+	PersonID   int64  `json:"person_id"`
+	PersonName string `json:"person_name,omitempty"`
+	Role       string `json:"role"`
+	Character  string `json:"character,omitempty"`
+}
+
+// ValidateCredits checks the full list of credits of a movie.
+func ValidateCredits(v *validator.Validator, credits []*Credit) {
+	v.Check(credits != nil, "credits", "must be provided")
+	v.Check(len(credits) <= 500, "credits", "must not contain more than 500 credits")
+
+	seen := make(map[Credit]bool)
+
+	for i, credit := range credits {
+		key := fmt.Sprintf("credits[%d]", i)
+
+		v.Check(credit.PersonID > 0, key+".person_id", "must be provided")
+		v.Check(
+			validator.PermittedValue(credit.Role, RoleDirector, RoleActor, RoleWriter),
+			key+".role",
+			"must be one of director, actor or writer",
+		)
+
+		if credit.Role == RoleActor {
+			v.Check(credit.Character != "", key+".character", "must be provided for actors")
+			v.Check(len(credit.Character) <= 500, key+".character", "must not be more than 500 bytes long")
+		} else {
+			v.Check(credit.Character == "", key+".character", "must only be provided for actors")
+		}
+
+		identity := Credit{PersonID: credit.PersonID, Role: credit.Role, Character: credit.Character}
+		v.Check(!seen[identity], key, "must not duplicate another credit")
+		seen[identity] = true
+	}
+}
+
+type CreditModelInterface interface {
+	GetAllForMovie(movieID int64) ([]*Credit, error)
+	GetAllForMovies(movieIDs []int64) ([]*Credit, error)
+	GetAllForPerson(personID int64) ([]*Credit, error)
+	SetForMovie(movieID int64, credits []*Credit) error
+}
+
+type CreditModel struct {
+	DB *sql.DB
+}
+
+// GetAllForMovie returns the credits of a movie in billing order.
+func (m CreditModel) GetAllForMovie(movieID int64) ([]*Credit, error) {
+	query := `
+		SELECT c.movie_id, c.person_id, p.name, c.role, c.character_name
+		FROM "Credits" c
+		INNER JOIN "People" p ON p.id = c.person_id
+		WHERE c.movie_id = $1
+		ORDER BY c.billing_order ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.PersonName,
+			&credit.Role,
+			&credit.Character,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// GetAllForMovies returns the credits of the given movies, grouped by movie and
+// in billing order within each movie.
+func (m CreditModel) GetAllForMovies(movieIDs []int64) ([]*Credit, error) {
+	query := `
+		SELECT c.movie_id, c.person_id, p.name, c.role, c.character_name
+		FROM "Credits" c
+		INNER JOIN "People" p ON p.id = c.person_id
+		WHERE c.movie_id = ANY($1)
+		ORDER BY c.movie_id ASC, c.billing_order ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.MovieID,
+			&credit.PersonID,
+			&credit.PersonName,
+			&credit.Role,
+			&credit.Character,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// GetAllForPerson returns the credits of a person, most recent movies first.
+// Credits on movies in the trash or unpublished are left out.
+func (m CreditModel) GetAllForPerson(personID int64) ([]*Credit, error) {
+	query := fmt.Sprintf(`
+		SELECT c.movie_id, m.title, c.person_id, c.role, c.character_name
+		FROM "Credits" c
+		INNER JOIN "Movies" m ON m.id = c.movie_id
+		WHERE c.person_id = $1 AND m.deleted_at IS NULL AND %s
+		ORDER BY m.year DESC, m.id ASC, c.billing_order ASC`, publishedMovieSQL("m"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, personID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		var credit Credit
+		err := rows.Scan(
+			&credit.MovieID,
+			&credit.MovieTitle,
+			&credit.PersonID,
+			&credit.Role,
+			&credit.Character,
+		)
+		if err != nil {
+			return nil, err
+		}
+		credits = append(credits, &credit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+// SetForMovie replaces all the credits of a movie in a single transaction. The
+// credits' positions in the slice become their billing order.
+func (m CreditModel) SetForMovie(movieID int64, credits []*Credit) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM "Credits"
+		WHERE movie_id = $1`
+
+	_, err = tx.ExecContext(ctx, query, movieID)
+	if err != nil {
+		return err
+	}
+
+	personIDs := make([]int64, len(credits))
+	roles := make([]string, len(credits))
+	characters := make([]string, len(credits))
+	for i, credit := range credits {
+		personIDs[i] = credit.PersonID
+		roles[i] = credit.Role
+		characters[i] = credit.Character
+	}
+
+	query = `
+		INSERT INTO "Credits" (movie_id, person_id, role, character_name, billing_order)
+		SELECT $1, credit.person_id, credit.role, credit.character_name, credit.billing_order
+		FROM UNNEST($2::BIGINT[], $3::TEXT[], $4::TEXT[])
+			WITH ORDINALITY AS credit (person_id, role, character_name, billing_order)`
+	args := []any{
+		movieID,
+		pq.Array(personIDs),
+		pq.Array(roles),
+		pq.Array(characters),
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "Credits" violates foreign key constraint "Credits_person_id_fkey"`:
+			return ErrUnknownPerson
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
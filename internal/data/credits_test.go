@@ -0,0 +1,148 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateCredits(t *testing.T) {
+	tests := []struct {
+		name     string
+		credits  []*Credit
+		expected map[string]string
+	}{
+		{
+			name: "Valid",
+			credits: []*Credit{
+				{PersonID: 1, Role: RoleDirector},
+				{PersonID: 1, Role: RoleWriter},
+				{PersonID: 2, Role: RoleActor, Character: "T'Challa"},
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "Invalid",
+			credits: []*Credit{
+				{PersonID: 1, Role: RoleDirector, Character: "Himself"},
+				{PersonID: 2, Role: RoleActor},
+				{Role: "producer"},
+				{PersonID: 1, Role: RoleDirector, Character: "Himself"},
+			},
+			expected: map[string]string{
+				"credits[0].character": "must only be provided for actors",
+				"credits[1].character": "must be provided for actors",
+				"credits[2].person_id": "must be provided",
+				"credits[2].role":      "must be one of director, actor or writer",
+				"credits[3].character": "must only be provided for actors",
+				"credits[3]":           "must not duplicate another credit",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateCredits(v, test.credits)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestCreditModel_SetForMovie(t *testing.T) {
+	deleteQuery := `
+		DELETE FROM "Credits"
+		WHERE movie_id = \$1`
+	insertQuery := `
+		INSERT INTO "Credits" \(movie_id, person_id, role, character_name, billing_order\)
+		SELECT \$1, credit.person_id, credit.role, credit.character_name, credit.billing_order
+		FROM UNNEST\(\$2::BIGINT\[\], \$3::TEXT\[\], \$4::TEXT\[\]\)
+			WITH ORDINALITY AS credit \(person_id, role, character_name, billing_order\)`
+	credits := []*Credit{
+		{PersonID: 1, Role: RoleDirector},
+		{PersonID: 2, Role: RoleActor, Character: "T'Challa"},
+	}
+	args := []driver.Value{
+		1,
+		pq.Array([]int64{1, 2}),
+		pq.Array([]string{RoleDirector, RoleActor}),
+		pq.Array([]string{"", "T'Challa"}),
+	}
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model CreditModel)
+	}{
+		{
+			name: "Success",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(insertQuery).WithArgs(args...).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model CreditModel) {
+				err := model.SetForMovie(1, credits)
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "ErrUnknownPerson",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(deleteQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(insertQuery).WithArgs(args...).WillReturnError(errors.New(
+					`pq: insert or update on table "Credits" violates foreign key constraint "Credits_person_id_fkey"`,
+				))
+				mock.ExpectRollback()
+			},
+			checkModel: func(model CreditModel) {
+				err := model.SetForMovie(1, credits)
+				assert.Equal(t, ErrUnknownPerson, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := CreditModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCreditModel_GetAllForMovies(t *testing.T) {
+	query := `
+		SELECT c.movie_id, c.person_id, p.name, c.role, c.character_name
+		FROM "Credits" c
+		INNER JOIN "People" p ON p.id = c.person_id
+		WHERE c.movie_id = ANY\(\$1\)
+		ORDER BY c.movie_id ASC, c.billing_order ASC`
+
+	db, mock := NewMock(t)
+	model := CreditModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{"movie_id", "person_id", "name", "role", "character_name"}).
+		AddRow(1, 5, "Ryan Coogler", RoleDirector, "").
+		AddRow(2, 6, "Auli'i Cravalho", RoleActor, "Moana")
+	mock.ExpectQuery(query).WithArgs(pq.Array([]int64{1, 2})).WillReturnRows(rows)
+
+	credits, err := model.GetAllForMovies([]int64{1, 2})
+	assert.Nil(t, err)
+	assert.Equal(t, []*Credit{
+		{MovieID: 1, PersonID: 5, PersonName: "Ryan Coogler", Role: RoleDirector},
+		{MovieID: 2, PersonID: 6, PersonName: "Auli'i Cravalho", Role: RoleActor, Character: "Moana"},
+	}, credits)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
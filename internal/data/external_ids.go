@@ -0,0 +1,177 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateExternalID = errors.New("duplicate external id")
+)
+
+// The sources of external identifiers that movies can be linked to.
+const (
+	SourceIMDb     = "imdb"
+	SourceTMDB     = "tmdb"
+	SourceWikidata = "wikidata"
+)
+
+// externalIDFormats holds the format of the identifiers of each source.
+var externalIDFormats = map[string]*regexp.Regexp{
+	SourceIMDb:     regexp.MustCompile(`^tt[0-9]{7,10}$`),
+	SourceTMDB:     regexp.MustCompile(`^[1-9][0-9]*$`),
+	SourceWikidata: regexp.MustCompile(`^Q[1-9][0-9]*$`),
+}
+
+// externalIDIndexes holds the names of the unique indexes on the identifiers of
+// each source, which show up in the errors of duplicate inserts.
+var externalIDIndexes = []string{
+	"movies_external_ids_imdb_index",
+	"movies_external_ids_tmdb_index",
+	"movies_external_ids_wikidata_index",
+}
+
+// ExternalIDs maps the sources that a movie is listed in, such as "imdb", to
+// the movie's identifier there. Each identifier belongs to at most one movie.
+type ExternalIDs map[string]string
+
+// Value stores the identifiers in a JSONB column.
+func (ids ExternalIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]string(ids))
+}
+
+// Scan reads the identifiers from a JSONB column.
+func (ids *ExternalIDs) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, (*map[string]string)(ids))
+	case string:
+		return json.Unmarshal([]byte(src), (*map[string]string)(ids))
+	default:
+		return errors.New("unsupported external_ids column type")
+	}
+}
+
+func ValidateExternalIDs(v *validator.Validator, ids ExternalIDs) {
+	for source, id := range ids {
+		format, ok := externalIDFormats[source]
+		if !ok {
+			v.AddError("external_ids", fmt.Sprintf("contains unknown source: %s", source))
+			continue
+		}
+		v.Check(format.MatchString(id), "external_ids."+source, "must be a valid identifier")
+	}
+}
+
+// ParseExternalID parses the value of an external_id query string parameter,
+// which is either "<source>:<id>" or an identifier whose source can be told
+// from its format, such as "tt0111161" for IMDb or "Q172241" for Wikidata.
+// TMDB identifiers are plain numbers, so they always need the "tmdb:" prefix.
+func ParseExternalID(s string) (ExternalIDs, bool) {
+	if source, id, ok := strings.Cut(s, ":"); ok {
+		format, ok := externalIDFormats[source]
+		if !ok || !format.MatchString(id) {
+			return nil, false
+		}
+		return ExternalIDs{source: id}, true
+	}
+
+	for _, source := range []string{SourceIMDb, SourceWikidata} {
+		if externalIDFormats[source].MatchString(s) {
+			return ExternalIDs{source: s}, true
+		}
+	}
+
+	return nil, false
+}
+
+// isDuplicateExternalID reports whether err is a violation of the unique index
+// on the identifiers of any source.
+func isDuplicateExternalID(err error) bool {
+	for _, index := range externalIDIndexes {
+		if err.Error() == fmt.Sprintf(`pq: duplicate key value violates unique constraint "%s"`, index) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedTitleSQL normalizes a title for duplicate detection by lowercasing
+// it and dropping everything but letters and digits, so that "Moana",
+// "MOANA" and "Moana!" all compare equal.
+const normalizedTitleSQL = `REGEXP_REPLACE(LOWER(%s), '[^[:alnum:]]+', '', 'g')`
+
+// FindDuplicates returns the movies that are likely to be duplicates of the
+// given one: movies from the same year whose titles are equal after
+// normalization. The movie itself is never returned. When live is true, only
+// live movies are considered, so that callers who may not see the others
+// aren't told about them.
+func (m MovieModel) FindDuplicates(movie *Movie, live bool) ([]*Movie, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids
+		FROM "Movies"
+		WHERE
+			deleted_at IS NULL
+			AND id <> $1
+			AND year = $2
+			AND %s = %s
+			AND (NOT $4 OR (%s))
+		ORDER BY id ASC
+		LIMIT 5`,
+		fmt.Sprintf(normalizedTitleSQL, "title"),
+		fmt.Sprintf(normalizedTitleSQL, "$3"),
+		publishedMovieSQL(`"Movies"`),
+	)
+	args := []any{movie.ID, movie.Year, movie.Title, live}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.RatingAverage,
+			&movie.RatingCount,
+			&movie.Poster,
+			&movie.ExternalIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
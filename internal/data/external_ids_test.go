@@ -0,0 +1,104 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateExternalIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      ExternalIDs
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			ids:      ExternalIDs{"imdb": "tt0111161", "tmdb": "278", "wikidata": "Q172241"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "InvalidIMDb",
+			ids:      ExternalIDs{"imdb": "0111161"},
+			expected: map[string]string{"external_ids.imdb": "must be a valid identifier"},
+		},
+		{
+			name:     "InvalidTMDB",
+			ids:      ExternalIDs{"tmdb": "0278"},
+			expected: map[string]string{"external_ids.tmdb": "must be a valid identifier"},
+		},
+		{
+			name:     "UnknownSource",
+			ids:      ExternalIDs{"letterboxd": "the-shawshank-redemption"},
+			expected: map[string]string{"external_ids": "contains unknown source: letterboxd"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateExternalIDs(v, test.ids)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestParseExternalID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ExternalIDs
+		ok       bool
+	}{
+		{input: "imdb:tt0111161", expected: ExternalIDs{"imdb": "tt0111161"}, ok: true},
+		{input: "tmdb:278", expected: ExternalIDs{"tmdb": "278"}, ok: true},
+		{input: "tt0111161", expected: ExternalIDs{"imdb": "tt0111161"}, ok: true},
+		{input: "Q172241", expected: ExternalIDs{"wikidata": "Q172241"}, ok: true},
+		{input: "278"},
+		{input: "tmdb:tt0111161"},
+		{input: "letterboxd:278"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			ids, ok := ParseExternalID(test.input)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
+
+func TestMovieModel_FindDuplicates(t *testing.T) {
+	query := `
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids
+		FROM "Movies"
+		WHERE
+			deleted_at IS NULL
+			AND id <> \$1
+			AND year = \$2
+			AND REGEXP_REPLACE\(LOWER\(title\), '\[\^\[:alnum:\]\]\+', '', 'g'\) = REGEXP_REPLACE\(LOWER\(\$3\), '\[\^\[:alnum:\]\]\+', '', 'g'\)
+			AND \(NOT \$4 OR \("Movies".status = 'published' AND "Movies".publish_at <= NOW\(\) AND \("Movies".unpublish_at IS NULL OR "Movies".unpublish_at > NOW\(\)\)\)\)
+		ORDER BY id ASC
+		LIMIT 5`
+
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "created_at", "title", "year", "runtime", "genres", "version",
+		"rating_average", "rating_count", "poster", "external_ids",
+	}).AddRow(2, time.Now(), "Moana", 2016, 107, pq.Array([]string{"animation"}), 1, "0.00", 0, nil, "{}")
+	mock.ExpectQuery(query).WithArgs(0, 2016, "MOANA!", true).WillReturnRows(rows)
+
+	movies, err := model.FindDuplicates(&Movie{Title: "MOANA!", Year: 2016}, true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(movies))
+	assert.Equal(t, int64(2), movies[0].ID)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
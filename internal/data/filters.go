@@ -1,11 +1,16 @@
 package data
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/walkccc/greenlight/internal/validator"
 )
 
+// Filters holds the pagination and sort parameters of a listing. Sort is a
+// comma-separated list of entries from SortSafeValues, such as "-year,title",
+// each of which is a column name optionally prefixed with "-" for descending
+// order.
 type Filters struct {
 	Page           int
 	PageSize       int
@@ -13,26 +18,35 @@ type Filters struct {
 	SortSafeValues []string
 }
 
-// sortColumn extracts the column name from the Sort field if it matches one of
-// the entries in SortSafeValues.
-func (f Filters) sortColumn() string {
-	for _, sortSafeValue := range f.SortSafeValues {
-		if f.Sort == sortSafeValue {
-			return strings.TrimPrefix(f.Sort, "-")
+// sortValues splits the Sort field into its entries.
+func (f Filters) sortValues() []string {
+	return strings.Split(f.Sort, ",")
+}
+
+// orderBy returns the ORDER BY expressions for the Sort field, such as
+// "year DESC, title ASC", with every column name prefixed by prefix, which is
+// either empty or a table alias followed by a dot. The expressions are only
+// ever made of the entries of SortSafeValues, never of the raw request input.
+func (f Filters) orderBy(prefix string) string {
+	expressions := []string{}
+
+	for _, value := range f.sortValues() {
+		index := slices.Index(f.SortSafeValues, value)
+		if index == -1 {
+			// A sensible failsafe to help stop a SQL injection attack.
+			panic("unsafe sort parameter: " + f.Sort)
 		}
-	}
 
-	// A sensible failsafe to help stop a SQL injection attack.
-	panic("unsafe sort parameter: " + f.Sort)
-}
+		safeValue := f.SortSafeValues[index]
+		direction := "ASC"
+		if strings.HasPrefix(safeValue, "-") {
+			direction = "DESC"
+		}
 
-// sortDirection returns the sort direction ("ASC" or "DESC") depending on the
-// prefix character of Sort field.
-func (f Filters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
-		return "DESC"
+		expressions = append(expressions, prefix+strings.TrimPrefix(safeValue, "-")+" "+direction)
 	}
-	return "ASC"
+
+	return strings.Join(expressions, ", ")
 }
 
 func (f Filters) limit() int {
@@ -48,7 +62,23 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.Page <= 10_000_000, "page", "must be a maximum of 10 million")
 	v.Check(f.PageSize > 0, "page_size", "must be greater than 0")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
-	v.Check(validator.PermittedValue(f.Sort, f.SortSafeValues...), "sort", "invalid sort value")
+	ValidateSort(v, f)
+}
+
+// ValidateSort checks that every entry of the Sort field is one of
+// SortSafeValues, and that no column is sorted by twice.
+func ValidateSort(v *validator.Validator, f Filters) {
+	columns := []string{}
+
+	for _, value := range f.sortValues() {
+		if !validator.PermittedValue(value, f.SortSafeValues...) {
+			v.AddError("sort", "invalid sort value")
+			return
+		}
+		columns = append(columns, strings.TrimPrefix(value, "-"))
+	}
+
+	v.Check(validator.Unique(columns), "sort", "must not sort by the same field twice")
 }
 
 type Metadata struct {
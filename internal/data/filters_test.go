@@ -0,0 +1,63 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestFilters_orderBy(t *testing.T) {
+	safeValues := []string{"id", "title", "year", "-id", "-title", "-year"}
+
+	tests := []struct {
+		name   string
+		sort   string
+		prefix string
+		want   string
+	}{
+		{name: "SingleField", sort: "-year", want: "year DESC"},
+		{name: "MultipleFields", sort: "-year,title", want: "year DESC, title ASC"},
+		{name: "Prefix", sort: "title,-id", prefix: "m.", want: "m.title ASC, m.id DESC"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filters := Filters{Sort: test.sort, SortSafeValues: safeValues}
+			assert.Equal(t, test.want, filters.orderBy(test.prefix))
+		})
+	}
+
+	t.Run("UnsafeField", func(t *testing.T) {
+		filters := Filters{Sort: "title,year; DROP TABLE users", SortSafeValues: safeValues}
+		assert.Panics(t, func() { filters.orderBy("") })
+	})
+}
+
+func TestValidateSort(t *testing.T) {
+	safeValues := []string{"id", "title", "year", "-id", "-title", "-year"}
+
+	tests := []struct {
+		name  string
+		sort  string
+		error string
+	}{
+		{name: "SingleField", sort: "title"},
+		{name: "MultipleFields", sort: "-year,title,id"},
+		{name: "UnsafeField", sort: "-year,rating", error: "invalid sort value"},
+		{name: "EmptyField", sort: "-year,", error: "invalid sort value"},
+		{name: "DuplicateField", sort: "-year,title,year", error: "must not sort by the same field twice"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateSort(v, Filters{Sort: test.sort, SortSafeValues: safeValues})
+			if test.error == "" {
+				assert.True(t, v.Valid())
+			} else {
+				assert.Equal(t, test.error, v.Errors["sort"])
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateGenre = errors.New("duplicate genre")
+)
+
+// SlugRX is a regex for sanity checking the format of genre slugs, e.g.
+// "sci-fi" or "film-noir".
+var SlugRX = regexp.MustCompile("^[a-z0-9]+(?:-[a-z0-9]+)*$")
+
+// nonSlugRX matches any run of characters that can't appear in a slug.
+var nonSlugRX = regexp.MustCompile("[^a-z0-9]+")
+
+// Genre holds a canonical genre. Movies store the Slug in their genres column,
+// while Aliases holds alternative spellings (e.g. "science fiction" for
+// "sci-fi") that are normalized to the Slug on input.
+type Genre struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	Aliases   []string  `json:"aliases"`
+	Version   int32     `json:"version"`
+}
+
+// Slugify lowercases s and replaces every run of non-alphanumeric characters
+// with a single "-", so "Sci Fi", "sci_fi" and "SCI-FI" all become "sci-fi".
+// Note that this must stay in sync with the migration that created the
+// "Genres" table.
+func Slugify(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = nonSlugRX.ReplaceAllString(s, "-")
+	return strings.Trim(s, "-")
+}
+
+// GenreCatalogue maps the slugified form of every genre slug and alias to its
+// canonical slug.
+type GenreCatalogue map[string]string
+
+// NewGenreCatalogue builds a GenreCatalogue from the provided genres.
+func NewGenreCatalogue(genres []*Genre) GenreCatalogue {
+	c := make(GenreCatalogue)
+	for _, genre := range genres {
+		c[Slugify(genre.Slug)] = genre.Slug
+		for _, alias := range genre.Aliases {
+			c[Slugify(alias)] = genre.Slug
+		}
+	}
+	return c
+}
+
+// Lookup returns the canonical slug for a genre name or alias.
+func (c GenreCatalogue) Lookup(name string) (string, bool) {
+	slug, ok := c[Slugify(name)]
+	return slug, ok
+}
+
+// Normalize replaces every known genre name in genres with its canonical slug
+// and returns the names that are unknown to the catalogue.
+func (c GenreCatalogue) Normalize(genres []string) []string {
+	var unknown []string
+	for i, name := range genres {
+		slug, ok := c.Lookup(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		genres[i] = slug
+	}
+	return unknown
+}
+
+func ValidateGenre(v *validator.Validator, genre *Genre) {
+	v.Check(genre.Slug != "", "slug", "must be provided")
+	v.Check(len(genre.Slug) <= 50, "slug", "must not be more than 50 bytes long")
+	v.Check(validator.Matches(genre.Slug, SlugRX), "slug", "must only contain lowercase letters, digits and single dashes")
+
+	v.Check(genre.Name != "", "name", "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", "must not be more than 100 bytes long")
+
+	v.Check(genre.Aliases != nil, "aliases", "must be provided")
+	v.Check(len(genre.Aliases) <= 20, "aliases", "must not contain more than 20 aliases")
+	for _, alias := range genre.Aliases {
+		v.Check(Slugify(alias) != "", "aliases", "must not contain empty values")
+		v.Check(Slugify(alias) != genre.Slug, "aliases", "must not contain the slug itself")
+	}
+	v.Check(validator.Unique(genre.Aliases), "aliases", "must not contain duplicate values")
+}
+
+type GenreModelInterface interface {
+	Create(genre *Genre) error
+	Get(id int64) (*Genre, error)
+	GetAll() ([]*Genre, error)
+	Catalogue() (GenreCatalogue, error)
+	Update(genre *Genre) error
+	Merge(source, target *Genre) error
+}
+
+type GenreModel struct {
+	DB *sql.DB
+}
+
+func (m GenreModel) Create(genre *Genre) error {
+	query := `
+		INSERT INTO "Genres" (slug, name, aliases)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+	args := []any{
+		genre.Slug,
+		genre.Name,
+		pq.Array(genre.Aliases),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).
+		Scan(&genre.ID, &genre.CreatedAt, &genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "Genres_slug_key"`:
+			return ErrDuplicateGenre
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m GenreModel) Get(id int64) (*Genre, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, slug, name, aliases, version
+		FROM "Genres"
+		WHERE id = $1`
+
+	var genre Genre
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&genre.ID,
+		&genre.CreatedAt,
+		&genre.Slug,
+		&genre.Name,
+		pq.Array(&genre.Aliases),
+		&genre.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &genre, nil
+}
+
+func (m GenreModel) GetAll() ([]*Genre, error) {
+	query := `
+		SELECT id, created_at, slug, name, aliases, version
+		FROM "Genres"
+		ORDER BY slug ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+
+	for rows.Next() {
+		var genre Genre
+		err := rows.Scan(
+			&genre.ID,
+			&genre.CreatedAt,
+			&genre.Slug,
+			&genre.Name,
+			pq.Array(&genre.Aliases),
+			&genre.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return genres, nil
+}
+
+// Catalogue returns a GenreCatalogue of all the genres in the database.
+func (m GenreModel) Catalogue() (GenreCatalogue, error) {
+	genres, err := m.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return NewGenreCatalogue(genres), nil
+}
+
+// Update saves the genre and, if its slug has been renamed, rewrites the
+// genres of every movie that referenced the old slug (recording a new,
+// editor-less version for each of them). Both happen in a single transaction.
+func (m GenreModel) Update(genre *Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldSlug string
+
+	query := `
+		SELECT slug
+		FROM "Genres"
+		WHERE id = $1 AND version = $2
+		FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, genre.ID, genre.Version).Scan(&oldSlug)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query = `
+		UPDATE "Genres"
+		SET slug = $1, name = $2, aliases = $3, version = version + 1
+		WHERE id = $4
+		RETURNING version`
+	args := []any{
+		genre.Slug,
+		genre.Name,
+		pq.Array(genre.Aliases),
+		genre.ID,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&genre.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "Genres_slug_key"`:
+			return ErrDuplicateGenre
+		default:
+			return err
+		}
+	}
+
+	if oldSlug != genre.Slug {
+		query = `
+			WITH movie AS (
+				UPDATE "Movies"
+				SET genres = ARRAY_REPLACE(genres, $1, $2), version = version + 1
+				WHERE $1 = ANY(genres)
+				RETURNING id, title, year, runtime, genres, version
+			)
+			INSERT INTO "MovieVersions" (movie_id, version, title, year, runtime, genres)
+			SELECT id, version, title, year, runtime, genres
+			FROM movie`
+
+		_, err = tx.ExecContext(ctx, query, oldSlug, genre.Slug)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Merge folds the source genre into the target genre: every movie tagged with
+// the source is re-tagged with the target (without duplicating it), the
+// source's slug and aliases become aliases of the target, and the source is
+// deleted.
+func (m GenreModel) Merge(source, target *Genre) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		WITH movie AS (
+			UPDATE "Movies"
+			SET
+				genres = CASE
+					WHEN $2 = ANY(genres) THEN ARRAY_REMOVE(genres, $1)
+					ELSE ARRAY_REPLACE(genres, $1, $2)
+				END,
+				version = version + 1
+			WHERE $1 = ANY(genres)
+			RETURNING id, title, year, runtime, genres, version
+		)
+		INSERT INTO "MovieVersions" (movie_id, version, title, year, runtime, genres)
+		SELECT id, version, title, year, runtime, genres
+		FROM movie`
+
+	_, err = tx.ExecContext(ctx, query, source.Slug, target.Slug)
+	if err != nil {
+		return err
+	}
+
+	aliases := append([]string{}, target.Aliases...)
+	for _, alias := range append([]string{source.Slug}, source.Aliases...) {
+		if !validator.PermittedValue(alias, aliases...) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	query = `
+		UPDATE "Genres"
+		SET aliases = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+	args := []any{
+		pq.Array(aliases),
+		target.ID,
+		target.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&target.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query = `
+		DELETE FROM "Genres"
+		WHERE id = $1 AND version = $2`
+
+	result, err := tx.ExecContext(ctx, query, source.ID, source.Version)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	target.Aliases = aliases
+	return tx.Commit()
+}
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "sci-fi", Slugify("sci-fi"))
+	assert.Equal(t, "sci-fi", Slugify("Sci-Fi"))
+	assert.Equal(t, "sci-fi", Slugify("  SCI  fi "))
+	assert.Equal(t, "science-fiction", Slugify("Science Fiction!"))
+	assert.Equal(t, "", Slugify("--"))
+}
+
+func TestGenreCatalogue(t *testing.T) {
+	catalogue := NewGenreCatalogue([]*Genre{
+		{Slug: "sci-fi", Aliases: []string{"science fiction", "scifi"}},
+		{Slug: "comedy"},
+	})
+
+	slug, ok := catalogue.Lookup("Science Fiction")
+	assert.True(t, ok)
+	assert.Equal(t, "sci-fi", slug)
+
+	_, ok = catalogue.Lookup("western")
+	assert.False(t, ok)
+
+	genres := []string{"SciFi", "Comedy", "western"}
+	unknown := catalogue.Normalize(genres)
+	assert.Equal(t, []string{"sci-fi", "comedy", "western"}, genres)
+	assert.Equal(t, []string{"western"}, unknown)
+}
+
+func TestValidateGenre(t *testing.T) {
+	t.Run("InvalidGenre", func(t *testing.T) {
+		genre := &Genre{
+			Slug:    "Sci Fi",                   // Invalid: not a slug
+			Name:    "",                         // Invalid: empty name
+			Aliases: []string{"scifi", "scifi"}, // Invalid: duplicate aliases
+		}
+
+		v := validator.New()
+		ValidateGenre(v, genre)
+		assert.False(t, v.Valid())
+
+		expectedErrors := map[string]string{
+			"slug":    "must only contain lowercase letters, digits and single dashes",
+			"name":    "must be provided",
+			"aliases": "must not contain duplicate values",
+		}
+		for field, expectedMessage := range expectedErrors {
+			actualMessage := v.Errors[field]
+			assert.Equal(t, expectedMessage, actualMessage)
+		}
+	})
+
+	t.Run("ValidGenre", func(t *testing.T) {
+		genre := &Genre{
+			Slug:    "sci-fi",
+			Name:    "Science Fiction",
+			Aliases: []string{"scifi"},
+		}
+
+		v := validator.New()
+		ValidateGenre(v, genre)
+		assert.True(t, v.Valid())
+	})
+}
+
+func TestGenreModel_GetAll(t *testing.T) {
+	query := `
+		SELECT id, created_at, slug, name, aliases, version
+		FROM "Genres"
+		ORDER BY slug ASC`
+	createdAt := time.Now()
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model GenreModel)
+	}{
+		{
+			name: "Success",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.
+					NewRows([]string{"id", "created_at", "slug", "name", "aliases", "version"}).
+					AddRow(1, createdAt, "comedy", "Comedy", "{}", 1).
+					AddRow(2, createdAt, "sci-fi", "Science Fiction", "{scifi}", 1)
+				mock.ExpectQuery(query).WillReturnRows(rows)
+			},
+			checkModel: func(model GenreModel) {
+				genres, err := model.GetAll()
+				assert.Nil(t, err)
+				assert.Equal(t, 2, len(genres))
+				assert.Equal(t, "sci-fi", genres[1].Slug)
+				assert.Equal(t, []string{"scifi"}, genres[1].Aliases)
+			},
+		},
+		{
+			name: "ErrConnDone",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WillReturnError(sql.ErrConnDone)
+			},
+			checkModel: func(model GenreModel) {
+				genres, err := model.GetAll()
+				assert.Nil(t, genres)
+				assert.Equal(t, sql.ErrConnDone, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := GenreModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+		})
+	}
+}
+
+func TestGenreModel_Merge(t *testing.T) {
+	source := &Genre{ID: 2, Slug: "scifi", Aliases: []string{"sf"}, Version: 1}
+	target := &Genre{ID: 1, Slug: "sci-fi", Aliases: []string{}, Version: 3}
+
+	db, mock := NewMock(t)
+	model := GenreModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "Movies"`).
+		WithArgs("scifi", "sci-fi").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectQuery(`UPDATE "Genres"`).
+		WithArgs(pq.Array([]string{"scifi", "sf"}), 1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+	mock.ExpectExec(`DELETE FROM "Genres"`).
+		WithArgs(2, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := model.Merge(source, target)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), target.Version)
+	assert.Equal(t, []string{"scifi", "sf"}, target.Aliases)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
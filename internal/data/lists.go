@@ -0,0 +1,568 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateList      = errors.New("duplicate list")
+	ErrDuplicateListEntry = errors.New("duplicate list entry")
+)
+
+// WatchlistName is the name given to a user's default list when it is first
+// created.
+const WatchlistName = "Watchlist"
+
+// List holds a user's ordered list of movies. Every user has a default list,
+// their watchlist, which is created on first use and can't be deleted. Lists
+// are private to their owner unless they are made public.
+type List struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Default   bool      `json:"default"`
+	Public    bool      `json:"public"`
+	Version   int32     `json:"version"`
+}
+
+// ListEntry holds a movie on a list. Positions start at 1 and have no gaps.
+type ListEntry struct {
+	ListID    int64      `json:"-"`
+	Movie     *Movie     `json:"movie"`
+	Position  int32      `json:"position"`
+	AddedAt   time.Time  `json:"added_at"`
+	Watched   bool       `json:"watched"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+}
+
+func ValidateList(v *validator.Validator, list *List) {
+	v.Check(list.Name != "", "name", "must be provided")
+	v.Check(len(list.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(list.Default || list.Name != WatchlistName, "name", "is reserved for the watchlist")
+}
+
+func ValidateListEntry(v *validator.Validator, entry *ListEntry) {
+	v.Check(entry.Position >= 0, "position", "must not be negative")
+
+	if entry.WatchedAt != nil {
+		v.Check(entry.Watched, "watched_at", "must only be provided for watched movies")
+		v.Check(!entry.WatchedAt.After(time.Now()), "watched_at", "must not be in the future")
+	}
+}
+
+type ListModelInterface interface {
+	Create(list *List) error
+	Get(id int64) (*List, error)
+	GetWatchlist(userID int64) (*List, error)
+	GetAllForUser(userID int64) ([]*List, error)
+	Update(list *List) error
+	Delete(id int64) error
+	GetEntries(listID int64, filters Filters) ([]*ListEntry, Metadata, error)
+	GetEntry(listID, movieID int64) (*ListEntry, error)
+	AddEntry(entry *ListEntry) error
+	UpdateEntry(entry *ListEntry) error
+	RemoveEntry(listID, movieID int64) error
+}
+
+type ListModel struct {
+	DB *sql.DB
+}
+
+func (m ListModel) Create(list *List) error {
+	query := `
+		INSERT INTO "Lists" (user_id, name, public)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, version`
+	args := []any{list.UserID, list.Name, list.Public}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID, &list.CreatedAt, &list.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "Lists_user_id_name_key"`:
+			return ErrDuplicateList
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m ListModel) Get(id int64) (*List, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, user_id, created_at, name, is_default, public, version
+		FROM "Lists"
+		WHERE id = $1`
+
+	var list List
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&list.ID,
+		&list.UserID,
+		&list.CreatedAt,
+		&list.Name,
+		&list.Default,
+		&list.Public,
+		&list.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &list, nil
+}
+
+// GetWatchlist returns the user's default list, creating it if it doesn't
+// exist yet.
+func (m ListModel) GetWatchlist(userID int64) (*List, error) {
+	// The no-op update makes RETURNING return the existing list on conflict.
+	query := `
+		INSERT INTO "Lists" (user_id, name, is_default)
+		VALUES ($1, $2, TRUE)
+		ON CONFLICT (user_id) WHERE is_default DO UPDATE SET is_default = TRUE
+		RETURNING id, user_id, created_at, name, is_default, public, version`
+
+	var list List
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID, WatchlistName).Scan(
+		&list.ID,
+		&list.UserID,
+		&list.CreatedAt,
+		&list.Name,
+		&list.Default,
+		&list.Public,
+		&list.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+// GetAllForUser returns all the lists of a user, the default list first.
+func (m ListModel) GetAllForUser(userID int64) ([]*List, error) {
+	query := `
+		SELECT id, user_id, created_at, name, is_default, public, version
+		FROM "Lists"
+		WHERE user_id = $1
+		ORDER BY is_default DESC, name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []*List{}
+
+	for rows.Next() {
+		var list List
+		err := rows.Scan(
+			&list.ID,
+			&list.UserID,
+			&list.CreatedAt,
+			&list.Name,
+			&list.Default,
+			&list.Public,
+			&list.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, &list)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (m ListModel) Update(list *List) error {
+	query := `
+		UPDATE "Lists"
+		SET name = $1, public = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version`
+	args := []any{list.Name, list.Public, list.ID, list.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&list.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case err.Error() == `pq: duplicate key value violates unique constraint "Lists_user_id_name_key"`:
+			return ErrDuplicateList
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete deletes a list and its entries. Default lists are never deleted.
+func (m ListModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		DELETE FROM "Lists"
+		WHERE id = $1 AND NOT is_default`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetEntries returns a page of the entries of a list. Movies in the trash or
+// unpublished are left out.
+func (m ListModel) GetEntries(listID int64, filters Filters) ([]*ListEntry, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(), e.list_id, e.position, e.added_at, e.watched, e.watched_at,
+			m.id, m.title, m.year, m.runtime, m.genres, m.version, m.rating_average, m.rating_count,
+			m.poster, m.external_ids
+		FROM "ListEntries" e
+		INNER JOIN "Movies" m ON m.id = e.movie_id
+		WHERE e.list_id = $1 AND m.deleted_at IS NULL AND %s
+		ORDER BY %s, e.position ASC
+		LIMIT $2 OFFSET $3`, publishedMovieSQL("m"), filters.orderBy(""))
+	args := []any{listID, filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecord := 0
+	entries := []*ListEntry{}
+
+	for rows.Next() {
+		entry := ListEntry{Movie: &Movie{}}
+		err := rows.Scan(
+			&totalRecord,
+			&entry.ListID,
+			&entry.Position,
+			&entry.AddedAt,
+			&entry.Watched,
+			&entry.WatchedAt,
+			&entry.Movie.ID,
+			&entry.Movie.Title,
+			&entry.Movie.Year,
+			&entry.Movie.Runtime,
+			pq.Array(&entry.Movie.Genres),
+			&entry.Movie.Version,
+			&entry.Movie.RatingAverage,
+			&entry.Movie.RatingCount,
+			&entry.Movie.Poster,
+			&entry.Movie.ExternalIDs,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		entries = append(entries, &entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecord, filters.Page, filters.PageSize)
+	return entries, metadata, nil
+}
+
+// GetEntry returns a single entry of a list. Only the ID of the entry's movie
+// is filled in.
+func (m ListModel) GetEntry(listID, movieID int64) (*ListEntry, error) {
+	query := `
+		SELECT position, added_at, watched, watched_at
+		FROM "ListEntries"
+		WHERE list_id = $1 AND movie_id = $2`
+
+	entry := ListEntry{ListID: listID, Movie: &Movie{ID: movieID}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, listID, movieID).Scan(
+		&entry.Position,
+		&entry.AddedAt,
+		&entry.Watched,
+		&entry.WatchedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &entry, nil
+}
+
+// AddEntry adds a movie to a list at the entry's position, moving the entries
+// from that position onwards down by one. A position of 0, or one past the end
+// of the list, appends the movie.
+func (m ListModel) AddEntry(entry *ListEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	size, err := lockList(ctx, tx, entry.ListID)
+	if err != nil {
+		return err
+	}
+
+	if entry.Position < 1 || entry.Position > size+1 {
+		entry.Position = size + 1
+	}
+
+	query := `
+		UPDATE "ListEntries"
+		SET position = position + 1
+		WHERE list_id = $1 AND position >= $2`
+
+	_, err = tx.ExecContext(ctx, query, entry.ListID, entry.Position)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO "ListEntries" (list_id, movie_id, position, watched, watched_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING added_at`
+	args := []any{
+		entry.ListID,
+		entry.Movie.ID,
+		entry.Position,
+		entry.Watched,
+		entry.WatchedAt,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&entry.AddedAt)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "ListEntries_pkey"`:
+			return ErrDuplicateListEntry
+		default:
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateEntry saves the watched flag of an entry and moves it to the entry's
+// position, shifting the entries in between. Positions past the end of the list
+// move the entry to the end.
+func (m ListModel) UpdateEntry(entry *ListEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	size, err := lockList(ctx, tx, entry.ListID)
+	if err != nil {
+		return err
+	}
+
+	var oldPosition int32
+
+	query := `
+		SELECT position
+		FROM "ListEntries"
+		WHERE list_id = $1 AND movie_id = $2`
+
+	err = tx.QueryRowContext(ctx, query, entry.ListID, entry.Movie.ID).Scan(&oldPosition)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if entry.Position < 1 || entry.Position > size {
+		entry.Position = size
+	}
+
+	// Close the gap left at the old position and open one at the new position.
+	query = `
+		UPDATE "ListEntries"
+		SET position = position
+			- CASE WHEN position > $2 THEN 1 ELSE 0 END
+			+ CASE WHEN position - CASE WHEN position > $2 THEN 1 ELSE 0 END >= $3 THEN 1 ELSE 0 END
+		WHERE list_id = $1 AND movie_id <> $4`
+	args := []any{entry.ListID, oldPosition, entry.Position, entry.Movie.ID}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE "ListEntries"
+		SET position = $1, watched = $2, watched_at = $3
+		WHERE list_id = $4 AND movie_id = $5`
+	args = []any{
+		entry.Position,
+		entry.Watched,
+		entry.WatchedAt,
+		entry.ListID,
+		entry.Movie.ID,
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveEntry removes a movie from a list, moving the entries after it up by
+// one.
+func (m ListModel) RemoveEntry(listID, movieID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = lockList(ctx, tx, listID)
+	if err != nil {
+		return err
+	}
+
+	var position int32
+
+	query := `
+		DELETE FROM "ListEntries"
+		WHERE list_id = $1 AND movie_id = $2
+		RETURNING position`
+
+	err = tx.QueryRowContext(ctx, query, listID, movieID).Scan(&position)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	query = `
+		UPDATE "ListEntries"
+		SET position = position - 1
+		WHERE list_id = $1 AND position > $2`
+
+	_, err = tx.ExecContext(ctx, query, listID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockList locks a list for the rest of the transaction, so that concurrent
+// changes to its entries can't give two of them the same position, and returns
+// the last position in use. Movies purged from the trash take their entries
+// with them and leave gaps, so the last position can exceed the number of
+// entries.
+func lockList(ctx context.Context, tx *sql.Tx, listID int64) (int32, error) {
+	query := `
+		SELECT id
+		FROM "Lists"
+		WHERE id = $1
+		FOR UPDATE`
+
+	var id int64
+
+	err := tx.QueryRowContext(ctx, query, listID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	query = `
+		SELECT COALESCE(MAX(position), 0)
+		FROM "ListEntries"
+		WHERE list_id = $1`
+
+	var size int32
+
+	err = tx.QueryRowContext(ctx, query, listID).Scan(&size)
+	return size, err
+}
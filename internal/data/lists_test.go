@@ -0,0 +1,233 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateList(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     *List
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			list:     &List{Name: "Best of the 70s"},
+			expected: map[string]string{},
+		},
+		{
+			name:     "MissingName",
+			list:     &List{},
+			expected: map[string]string{"name": "must be provided"},
+		},
+		{
+			name:     "ReservedName",
+			list:     &List{Name: WatchlistName},
+			expected: map[string]string{"name": "is reserved for the watchlist"},
+		},
+		{
+			name:     "Watchlist",
+			list:     &List{Name: WatchlistName, Default: true},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateList(v, test.list)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestValidateListEntry(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		entry    *ListEntry
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			entry:    &ListEntry{Watched: true, WatchedAt: &past},
+			expected: map[string]string{},
+		},
+		{
+			name:     "WatchedAtWithoutWatched",
+			entry:    &ListEntry{WatchedAt: &past},
+			expected: map[string]string{"watched_at": "must only be provided for watched movies"},
+		},
+		{
+			name:     "WatchedAtInFuture",
+			entry:    &ListEntry{Watched: true, WatchedAt: &future},
+			expected: map[string]string{"watched_at": "must not be in the future"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateListEntry(v, test.entry)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestListModel_GetWatchlist(t *testing.T) {
+	query := `
+		INSERT INTO "Lists" \(user_id, name, is_default\)
+		VALUES \(\$1, \$2, TRUE\)
+		ON CONFLICT \(user_id\) WHERE is_default DO UPDATE SET is_default = TRUE
+		RETURNING id, user_id, created_at, name, is_default, public, version`
+
+	db, mock := NewMock(t)
+	model := ListModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "created_at", "name", "is_default", "public", "version"}).
+		AddRow(3, 7, time.Now(), WatchlistName, true, false, 1)
+	mock.ExpectQuery(query).WithArgs(7, WatchlistName).WillReturnRows(rows)
+
+	list, err := model.GetWatchlist(7)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), list.ID)
+	assert.True(t, list.Default)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestListModel_AddEntry(t *testing.T) {
+	lockQuery := `
+		SELECT id
+		FROM "Lists"
+		WHERE id = \$1
+		FOR UPDATE`
+	countQuery := `
+		SELECT COALESCE\(MAX\(position\), 0\)
+		FROM "ListEntries"
+		WHERE list_id = \$1`
+	shiftQuery := `
+		UPDATE "ListEntries"
+		SET position = position \+ 1
+		WHERE list_id = \$1 AND position >= \$2`
+	insertQuery := `
+		INSERT INTO "ListEntries" \(list_id, movie_id, position, watched, watched_at\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5\)
+		RETURNING added_at`
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model ListModel)
+	}{
+		{
+			name: "Append",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec(shiftQuery).WithArgs(3, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(3, 1, 3, false, nil).
+					WillReturnRows(sqlmock.NewRows([]string{"added_at"}).AddRow(time.Now()))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model ListModel) {
+				entry := &ListEntry{ListID: 3, Movie: &Movie{ID: 1}, Position: 10}
+				err := model.AddEntry(entry)
+				assert.Nil(t, err)
+				assert.Equal(t, int32(3), entry.Position)
+			},
+		},
+		{
+			name: "Insert",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec(shiftQuery).WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(3, 1, 1, false, nil).
+					WillReturnRows(sqlmock.NewRows([]string{"added_at"}).AddRow(time.Now()))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model ListModel) {
+				entry := &ListEntry{ListID: 3, Movie: &Movie{ID: 1}, Position: 1}
+				err := model.AddEntry(entry)
+				assert.Nil(t, err)
+				assert.Equal(t, int32(1), entry.Position)
+			},
+		},
+		{
+			name: "ErrDuplicateListEntry",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+				mock.ExpectExec(shiftQuery).WithArgs(3, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(3, 1, 3, false, nil).
+					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "ListEntries_pkey"`))
+				mock.ExpectRollback()
+			},
+			checkModel: func(model ListModel) {
+				err := model.AddEntry(&ListEntry{ListID: 3, Movie: &Movie{ID: 1}})
+				assert.Equal(t, ErrDuplicateListEntry, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := ListModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestListModel_RemoveEntry(t *testing.T) {
+	lockQuery := `
+		SELECT id
+		FROM "Lists"
+		WHERE id = \$1
+		FOR UPDATE`
+	countQuery := `
+		SELECT COALESCE\(MAX\(position\), 0\)
+		FROM "ListEntries"
+		WHERE list_id = \$1`
+	deleteQuery := `
+		DELETE FROM "ListEntries"
+		WHERE list_id = \$1 AND movie_id = \$2
+		RETURNING position`
+	shiftQuery := `
+		UPDATE "ListEntries"
+		SET position = position - 1
+		WHERE list_id = \$1 AND position > \$2`
+
+	db, mock := NewMock(t)
+	model := ListModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery(countQuery).WithArgs(3).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(deleteQuery).WithArgs(3, 1).WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(2))
+	mock.ExpectExec(shiftQuery).WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := model.RemoveEntry(3, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
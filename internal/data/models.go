@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
@@ -10,18 +11,48 @@ var (
 	ErrEditConflict   = errors.New("edit conflict")
 )
 
+// querier is satisfied by both *sql.DB and *sql.Tx, so that a model's queries
+// can run either on their own or as part of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type Models struct {
-	Movies      MovieModelInterface
-	Users       UserModelInterface
-	Tokens      TokenModelInterface
-	Permissions PermissionModelInterface
+	Movies       MovieModelInterface
+	Versions     MovieVersionModelInterface
+	Translations MovieTranslationModelInterface
+	Related      RelatedMovieModelInterface
+	Genres       GenreModelInterface
+	People       PersonModelInterface
+	Credits      CreditModelInterface
+	Releases     ReleaseModelInterface
+	Reviews      ReviewModelInterface
+	Lists        ListModelInterface
+	Collections  CollectionModelInterface
+	Tags         TagModelInterface
+	Users        UserModelInterface
+	Tokens       TokenModelInterface
+	Permissions  PermissionModelInterface
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Movies:      MovieModel{DB: db},
-		Users:       UserModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Permissions: PermissionModel{DB: db},
+		Movies:       MovieModel{DB: db},
+		Versions:     MovieVersionModel{DB: db},
+		Translations: MovieTranslationModel{DB: db},
+		Related:      RelatedMovieModel{DB: db},
+		Genres:       GenreModel{DB: db},
+		People:       PersonModel{DB: db},
+		Credits:      CreditModel{DB: db},
+		Releases:     ReleaseModel{DB: db},
+		Reviews:      ReviewModel{DB: db},
+		Lists:        ListModel{DB: db},
+		Collections:  CollectionModel{DB: db},
+		Tags:         TagModel{DB: db},
+		Users:        UserModel{DB: db},
+		Tokens:       TokenModel{DB: db},
+		Permissions:  PermissionModel{DB: db},
 	}
 }
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// MovieStatus is the moderation status of a movie.
+type MovieStatus string
+
+const (
+	// MovieStatusDraft is for movies that their submitter is still working on.
+	MovieStatusDraft MovieStatus = "draft"
+	// MovieStatusPending is for movies waiting in the moderation queue.
+	MovieStatusPending MovieStatus = "pending"
+	// MovieStatusPublished is for movies shown to every reader.
+	MovieStatusPublished MovieStatus = "published"
+	// MovieStatusRejected is for movies that a moderator turned down. Their
+	// submitter can fix them and submit them again.
+	MovieStatusRejected MovieStatus = "rejected"
+)
+
+// MovieStatuses holds the names of every MovieStatus.
+var MovieStatuses = []string{
+	string(MovieStatusDraft),
+	string(MovieStatusPending),
+	string(MovieStatusPublished),
+	string(MovieStatusRejected),
+}
+
+// publishedMovieSQL returns the condition that a movie is published and live,
+// that is within its publication window, for the "Movies" table referred to as
+// table.
+func publishedMovieSQL(table string) string {
+	return fmt.Sprintf(
+		"%[1]s.status = 'published' AND %[1]s.publish_at <= NOW() AND (%[1]s.unpublish_at IS NULL OR %[1]s.unpublish_at > NOW())",
+		table,
+	)
+}
+
+// IsLive reports whether the movie is published and within its publication
+// window at the given time, as publishedMovieSQL does.
+func (movie *Movie) IsLive(now time.Time) bool {
+	if movie.Status != MovieStatusPublished {
+		return false
+	}
+	if movie.PublishAt != nil && movie.PublishAt.After(now) {
+		return false
+	}
+	return movie.UnpublishAt == nil || movie.UnpublishAt.After(now)
+}
+
+// ValidateModerationReason checks the reason a moderator gives for a decision.
+func ValidateModerationReason(v *validator.Validator, reason string) {
+	v.Check(reason != "", "reason", "must be provided")
+	v.Check(len(reason) <= 1_000, "reason", "must not be more than 1000 bytes long")
+}
+
+// SetStatus saves the movie's status and moderation reason, provided that its
+// status is still from and that it hasn't been edited since it was read.
+// Otherwise, ErrEditConflict is returned, so that two moderators can't both
+// act on the same submission, and a decision is never applied to a version
+// the moderator hasn't seen. Status changes aren't edits, so they don't
+// increment the movie's version.
+func (m MovieModel) SetStatus(movie *Movie, from MovieStatus) error {
+	query := `
+		UPDATE "Movies"
+		SET status = $1, moderation_reason = $2
+		WHERE id = $3 AND status = $4 AND version = $5 AND deleted_at IS NULL`
+	args := []any{movie.Status, movie.ModerationReason, movie.ID, from, movie.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.conn().ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// MarkLive records that the published movies whose publication date has come
+// went live, and returns them, so that each of them is only returned once. This
+// includes movies approved after their publication date, as well as new movies
+// published right away.
+func (m MovieModel) MarkLive() ([]*Movie, error) {
+	query := fmt.Sprintf(`
+		UPDATE "Movies"
+		SET went_live_at = NOW()
+		WHERE went_live_at IS NULL AND deleted_at IS NULL AND %s
+		RETURNING id, title, publish_at`, publishedMovieSQL(`"Movies"`))
+
+	// Like purging, this runs in the background and may touch many rows.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(&movie.ID, &movie.Title, &movie.PublishAt)
+		if err != nil {
+			return nil, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movies, nil
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovieModel_SetStatus(t *testing.T) {
+	query := `
+		UPDATE "Movies"
+		SET status = \$1, moderation_reason = \$2
+		WHERE id = \$3 AND status = \$4 AND version = \$5 AND deleted_at IS NULL`
+
+	movie := &Movie{ID: 1, Version: 3, Status: MovieStatusRejected, ModerationReason: "Duplicate of #2."}
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := MovieModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectExec(query).
+			WithArgs(MovieStatusRejected, "Duplicate of #2.", 1, MovieStatusPending, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, model.SetStatus(movie, MovieStatusPending))
+	})
+
+	t.Run("ErrEditConflict", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := MovieModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectExec(query).
+			WithArgs(MovieStatusRejected, "Duplicate of #2.", 1, MovieStatusPending, 3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.Equal(t, ErrEditConflict, model.SetStatus(movie, MovieStatusPending))
+	})
+}
+
+func TestMovie_IsLive(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name  string
+		movie Movie
+		live  bool
+	}{
+		{"Published", Movie{Status: MovieStatusPublished, PublishAt: &past}, true},
+		{"Pending", Movie{Status: MovieStatusPending, PublishAt: &past}, false},
+		{"Scheduled", Movie{Status: MovieStatusPublished, PublishAt: &future}, false},
+		{"Embargoed", Movie{Status: MovieStatusPublished, PublishAt: &past, UnpublishAt: &future}, true},
+		{"Unpublished", Movie{Status: MovieStatusPublished, PublishAt: &past, UnpublishAt: &past}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.live, test.movie.IsLive(now))
+		})
+	}
+}
+
+func TestMovieModel_MarkLive(t *testing.T) {
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	publishAt := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "title", "publish_at"}).
+		AddRow(1, "Dune: Part Two", publishAt)
+	mock.ExpectQuery(`
+		UPDATE "Movies"
+		SET went_live_at = NOW\(\)
+		WHERE went_live_at IS NULL AND deleted_at IS NULL AND "Movies".status = 'published' AND "Movies".publish_at <= NOW\(\) AND \("Movies".unpublish_at IS NULL OR "Movies".unpublish_at > NOW\(\)\)
+		RETURNING id, title, publish_at`).
+		WillReturnRows(rows)
+
+	movies, err := model.MarkLive()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(movies))
+	assert.Equal(t, "Dune: Part Two", movies[0].Title)
+	assert.Equal(t, publishAt, *movies[0].PublishAt)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exportColumns holds the columns of a CSV or TSV export. The title, year,
+// runtime and genres columns can be read back by NewMovieDecoder.
+var exportColumns = []string{"id", "title", "year", "runtime", "genres", "version"}
+
+// MovieEncoder writes movies one at a time to a stream, in the same spirit as
+// MovieDecoder. Close must be called once all the movies have been encoded, to
+// terminate the document and flush any buffered output.
+type MovieEncoder interface {
+	Encode(movie *Movie) error
+	Close() error
+}
+
+// NewMovieEncoder returns a MovieEncoder for the given format. CSV and TSV
+// exports start with a header row, while JSON exports are a single
+// {"movies": [...]} document. Runtimes are written in the given runtime
+// format, which defaults to a plain number of minutes in CSV and TSV exports
+// and to "<runtime> mins" in JSON exports, as in responses.
+func NewMovieEncoder(format string, w io.Writer, runtimeFormat RuntimeFormat) (MovieEncoder, error) {
+	switch format {
+	case FormatCSV, FormatTSV:
+		writer := csv.NewWriter(w)
+		if format == FormatTSV {
+			writer.Comma = '\t'
+		}
+
+		err := writer.Write(exportColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		if runtimeFormat == "" {
+			runtimeFormat = RuntimeFormatInteger
+		}
+
+		return &csvMovieEncoder{writer: writer, runtimeFormat: runtimeFormat}, nil
+
+	case FormatNDJSON, FormatJSON:
+		buf := bufio.NewWriter(w)
+
+		if format == FormatJSON {
+			_, err := buf.WriteString(`{"movies":[`)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return &jsonMovieEncoder{buf: buf, array: format == FormatJSON, runtimeFormat: runtimeFormat}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvMovieEncoder struct {
+	writer        *csv.Writer
+	runtimeFormat RuntimeFormat
+}
+
+func (e *csvMovieEncoder) Encode(movie *Movie) error {
+	return e.writer.Write([]string{
+		strconv.FormatInt(movie.ID, 10),
+		movie.Title,
+		strconv.FormatInt(int64(movie.Year), 10),
+		movie.Runtime.FormatText(e.runtimeFormat),
+		strings.Join(movie.Genres, ","),
+		strconv.FormatInt(int64(movie.Version), 10),
+	})
+}
+
+func (e *csvMovieEncoder) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonMovieEncoder writes movies either as NDJSON, one per line, or as the
+// elements of a JSON array.
+type jsonMovieEncoder struct {
+	buf           *bufio.Writer
+	array         bool
+	count         int
+	runtimeFormat RuntimeFormat
+}
+
+func (e *jsonMovieEncoder) Encode(movie *Movie) error {
+	movie.RuntimeFormat = e.runtimeFormat
+
+	js, err := json.Marshal(movie)
+	if err != nil {
+		return err
+	}
+
+	if e.array && e.count > 0 {
+		err = e.buf.WriteByte(',')
+		if err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	_, err = e.buf.Write(js)
+	if err != nil {
+		return err
+	}
+
+	if !e.array {
+		return e.buf.WriteByte('\n')
+	}
+	return nil
+}
+
+func (e *jsonMovieEncoder) Close() error {
+	if e.array {
+		_, err := e.buf.WriteString("]}\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.buf.Flush()
+}
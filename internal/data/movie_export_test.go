@@ -0,0 +1,162 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMovieEncoder(t *testing.T) {
+	movies := []*Movie{
+		{ID: 1, Title: "Black Panther", Year: 2018, Runtime: 134, Genres: []string{"action", "adventure"}, Version: 1},
+		{ID: 2, Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation"}, Version: 3},
+	}
+
+	tests := []struct {
+		name          string
+		format        string
+		runtimeFormat RuntimeFormat
+		expected      string
+	}{
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			expected: "id,title,year,runtime,genres,version\n" +
+				"1,Black Panther,2018,134,\"action,adventure\",1\n" +
+				"2,Moana,2016,107,animation,3\n",
+		},
+		{
+			name:          "CSVISO8601",
+			format:        FormatCSV,
+			runtimeFormat: RuntimeFormatISO8601,
+			expected: "id,title,year,runtime,genres,version\n" +
+				"1,Black Panther,2018,PT2H14M,\"action,adventure\",1\n" +
+				"2,Moana,2016,PT1H47M,animation,3\n",
+		},
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			expected: `{"id":1,"title":"Black Panther","year":2018,"genres":["action","adventure"],"version":1,"rating_average":0,"rating_count":0,"runtime":"134 mins"}` + "\n" +
+				`{"id":2,"title":"Moana","year":2016,"genres":["animation"],"version":3,"rating_average":0,"rating_count":0,"runtime":"107 mins"}` + "\n",
+		},
+		{
+			name:          "NDJSONInteger",
+			format:        FormatNDJSON,
+			runtimeFormat: RuntimeFormatInteger,
+			expected: `{"id":1,"title":"Black Panther","year":2018,"genres":["action","adventure"],"version":1,"rating_average":0,"rating_count":0,"runtime":134}` + "\n" +
+				`{"id":2,"title":"Moana","year":2016,"genres":["animation"],"version":3,"rating_average":0,"rating_count":0,"runtime":107}` + "\n",
+		},
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			expected: `{"movies":[` +
+				`{"id":1,"title":"Black Panther","year":2018,"genres":["action","adventure"],"version":1,"rating_average":0,"rating_count":0,"runtime":"134 mins"},` +
+				`{"id":2,"title":"Moana","year":2016,"genres":["animation"],"version":3,"rating_average":0,"rating_count":0,"runtime":"107 mins"}` +
+				"]}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sb strings.Builder
+
+			encoder, err := NewMovieEncoder(test.format, &sb, test.runtimeFormat)
+			assert.Nil(t, err)
+
+			for _, movie := range movies {
+				assert.Nil(t, encoder.Encode(movie))
+			}
+			assert.Nil(t, encoder.Close())
+			assert.Equal(t, test.expected, sb.String())
+		})
+	}
+
+	t.Run("EmptyJSON", func(t *testing.T) {
+		var sb strings.Builder
+
+		encoder, err := NewMovieEncoder(FormatJSON, &sb, "")
+		assert.Nil(t, err)
+		assert.Nil(t, encoder.Close())
+		assert.JSONEq(t, `{"movies":[]}`, sb.String())
+	})
+}
+
+func TestMovieModel_Export(t *testing.T) {
+	declare := `
+		DECLARE "MovieExport" NO SCROLL CURSOR FOR
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids
+		FROM "Movies"
+		WHERE
+			deleted_at IS NULL
+			AND \(
+				TO_TSVECTOR\('simple', title\) @@ PLAINTO_TSQUERY\('simple', \$1\)
+				OR id IN \(
+					SELECT movie_id FROM "MovieTranslations"
+					WHERE TO_TSVECTOR\(search_config, title \|\| ' ' \|\| synopsis\) @@ PLAINTO_TSQUERY\(search_config, \$1\)
+				\)
+				OR \$1 = ''
+			\)
+			AND \(genres @> \$2 OR \$2 = '{}'\)
+			AND \(id IN \(SELECT movie_id FROM "Credits" WHERE person_id = \$3\) OR \$3 = 0\)
+			AND \(external_ids @> \$4 OR \$4 = '{}'\)
+			AND \(id IN \(SELECT movie_id FROM "CollectionEntries" WHERE collection_id = \$5\) OR \$5 = 0\)
+			AND \(
+				id IN \(
+					SELECT mt.movie_id
+					FROM "MovieTags" mt
+					INNER JOIN "Tags" t ON t.id = mt.tag_id
+					WHERE t.name = ANY\(\$6\)
+					GROUP BY mt.movie_id
+					HAVING COUNT\(\*\) = CARDINALITY\(\$6\)
+				\)
+				OR \$6 = '{}'
+			\)
+			AND \(status = \$7 OR \$7 = ''\)
+			AND \(
+				\$7 <> 'published'
+				OR \(publish_at <= NOW\(\) AND \(unpublish_at IS NULL OR unpublish_at > NOW\(\)\)\)
+			\)
+			AND \(
+				id IN \(
+					SELECT movie_id FROM "Releases"
+					WHERE \(country = \$8 OR \$8 = ''\) AND \(released_on > CURRENT_DATE\) = \$9
+				\)
+				OR \(\$8 = '' AND NOT \$9\)
+			\)
+		ORDER BY year DESC, id ASC`
+	fetch := `FETCH 1000 FROM "MovieExport"`
+	filters := Filters{Sort: "-year", SortSafeValues: []string{"-year"}}
+
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(declare).
+		WithArgs("", pq.Array([]string{"adventure"}), 0, []byte("{}"), 0, pq.Array([]string{}), MovieStatusPublished, "", false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(fetch).WillReturnRows(
+		sqlmock.NewRows([]string{
+			"id", "created_at", "title", "year", "runtime", "genres", "version", "rating_average", "rating_count", "poster",
+			"external_ids",
+		}).
+			AddRow(1, time.Now(), "Black Panther", 2018, 134, pq.Array([]string{"action", "adventure"}), 1, "8.00", 3, nil, `{"imdb":"tt1825683"}`).
+			AddRow(2, time.Now(), "Moana", 2016, 107, pq.Array([]string{"adventure"}), 1, "0.00", 0, nil, "{}"),
+	)
+	mock.ExpectCommit()
+
+	titles := []string{}
+	err := model.Export(MovieCriteria{Genres: []string{"adventure"}, Status: MovieStatusPublished}, filters, func(movie *Movie) error {
+		titles = append(titles, movie.Title)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Black Panther", "Moana"}, titles)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
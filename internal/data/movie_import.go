@@ -0,0 +1,200 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// Constants for the formats supported by NewMovieDecoder and NewMovieEncoder.
+// FormatJSON is only supported for encoding.
+const (
+	FormatCSV    = "csv"
+	FormatTSV    = "tsv"
+	FormatNDJSON = "ndjson"
+	FormatJSON   = "json"
+)
+
+// ErrInvalidImportHeader is returned by NewMovieDecoder if the header row of a
+// CSV or TSV stream is missing or doesn't contain the expected columns.
+var ErrInvalidImportHeader = errors.New("invalid header row")
+
+// movieColumns holds the columns of a CSV or TSV import, in any order.
+var movieColumns = []string{"title", "year", "runtime", "genres"}
+
+// MovieDecoder reads movies one row at a time from a stream, so that imports
+// never need to hold the whole upload in memory.
+type MovieDecoder interface {
+	// Decode returns the movie in the next row, or io.EOF once the stream is
+	// exhausted. Problems with the row itself (e.g. a year that isn't a
+	// number) are recorded in v rather than returned, so that the caller can
+	// carry on with the next row, and the returned movie may be nil if the row
+	// couldn't be parsed at all. A returned error means the stream can't be
+	// read any further.
+	Decode(v *validator.Validator) (*Movie, error)
+}
+
+// NewMovieDecoder returns a MovieDecoder for the given format. For CSV and TSV
+// the header row is read straight away, and must name each of the title,
+// year, runtime and genres columns exactly once. Genres are separated by
+// commas within their column.
+func NewMovieDecoder(format string, r io.Reader) (MovieDecoder, error) {
+	switch format {
+	case FormatCSV, FormatTSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		if format == FormatTSV {
+			reader.Comma = '\t'
+			reader.LazyQuotes = true
+		}
+
+		header, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("%w: the stream is empty", ErrInvalidImportHeader)
+			}
+			return nil, err
+		}
+
+		columns := make(map[string]int)
+		for i, name := range header {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !validator.PermittedValue(name, movieColumns...) {
+				return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImportHeader, name)
+			}
+			if _, exists := columns[name]; exists {
+				return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidImportHeader, name)
+			}
+			columns[name] = i
+		}
+		for _, name := range movieColumns {
+			if _, exists := columns[name]; !exists {
+				return nil, fmt.Errorf("%w: missing column %q", ErrInvalidImportHeader, name)
+			}
+		}
+
+		return &csvMovieDecoder{reader: reader, columns: columns}, nil
+
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1_048_576)
+		return &ndjsonMovieDecoder{scanner: scanner}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+type csvMovieDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func (d *csvMovieDecoder) Decode(v *validator.Validator) (*Movie, error) {
+	record, err := d.reader.Read()
+	if err != nil {
+		var parseError *csv.ParseError
+
+		switch {
+		case errors.As(err, &parseError):
+			v.AddError("row", parseError.Err.Error())
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+
+	field := func(name string) string {
+		i := d.columns[name]
+		if i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	movie := &Movie{Title: field("title")}
+
+	if year := field("year"); year != "" {
+		num, err := strconv.ParseInt(year, 10, 32)
+		if err != nil {
+			v.AddError("year", "must be an integer value")
+		}
+		movie.Year = int32(num)
+	}
+
+	if runtime := field("runtime"); runtime != "" {
+		movie.Runtime, err = ParseRuntime(runtime)
+		if err != nil {
+			v.AddError("runtime", err.Error())
+		}
+	}
+
+	if genres := field("genres"); genres != "" {
+		movie.Genres = []string{}
+		for _, genre := range strings.Split(genres, ",") {
+			movie.Genres = append(movie.Genres, strings.TrimSpace(genre))
+		}
+	}
+
+	return movie, nil
+}
+
+type ndjsonMovieDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *ndjsonMovieDecoder) Decode(v *validator.Validator) (*Movie, error) {
+	var line []byte
+
+	// Skip blank lines, which are commonly left at the end of a file.
+	for len(line) == 0 {
+		if !d.scanner.Scan() {
+			if err := d.scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		line = bytes.TrimSpace(d.scanner.Bytes())
+	}
+
+	var input struct {
+		Title   string   `json:"title"`
+		Year    int32    `json:"year"`
+		Runtime Runtime  `json:"runtime"`
+		Genres  []string `json:"genres"`
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&input)
+	if err != nil {
+		var unmarshalTypeError *json.UnmarshalTypeError
+
+		switch {
+		case errors.As(err, &unmarshalTypeError) && unmarshalTypeError.Field != "":
+			v.AddError(unmarshalTypeError.Field, "incorrect JSON type")
+		case errors.Is(err, ErrInvalidRuntimeFormat):
+			v.AddError("runtime", err.Error())
+		default:
+			v.AddError("row", err.Error())
+		}
+		return nil, nil
+	}
+
+	return &Movie{
+		Title:   input.Title,
+		Year:    input.Year,
+		Runtime: input.Runtime,
+		Genres:  input.Genres,
+	}, nil
+}
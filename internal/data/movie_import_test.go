@@ -0,0 +1,126 @@
+package data
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestParseRuntime(t *testing.T) {
+	for _, s := range []string{"107 mins", "107", " 107mins ", "1h 47m", "1H47M", "PT107M", "PT1H47M"} {
+		runtime, err := ParseRuntime(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, Runtime(107), runtime, s)
+	}
+
+	for _, s := range []string{"", "1h 47", "PT", "PT1H47M30S", "two hours"} {
+		_, err := ParseRuntime(s)
+		assert.ErrorIs(t, err, ErrInvalidRuntimeFormat, s)
+	}
+}
+
+func TestNewMovieDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "CSV",
+			format: FormatCSV,
+			input:  "year,title,runtime,genres\n2018,Black Panther,134 mins,\"action, adventure\"\n",
+		},
+		{
+			name:   "TSV",
+			format: FormatTSV,
+			input:  "title\tyear\truntime\tgenres\nBlack Panther\t2018\t134\taction,adventure\n",
+		},
+		{
+			name:   "NDJSON",
+			format: FormatNDJSON,
+			input:  `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["action","adventure"]}` + "\n\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder, err := NewMovieDecoder(test.format, strings.NewReader(test.input))
+			assert.Nil(t, err)
+
+			v := validator.New()
+			movie, err := decoder.Decode(v)
+			assert.Nil(t, err)
+			assert.True(t, v.Valid())
+			assert.Equal(t, &Movie{
+				Title:   "Black Panther",
+				Year:    2018,
+				Runtime: 134,
+				Genres:  []string{"action", "adventure"},
+			}, movie)
+
+			_, err = decoder.Decode(validator.New())
+			assert.ErrorIs(t, err, io.EOF)
+		})
+	}
+
+	t.Run("InvalidRows", func(t *testing.T) {
+		input := "title,year,runtime,genres\nBlack Panther,twenty,2 hours,action\n"
+		decoder, err := NewMovieDecoder(FormatCSV, strings.NewReader(input))
+		assert.Nil(t, err)
+
+		v := validator.New()
+		_, err = decoder.Decode(v)
+		assert.Nil(t, err)
+		assert.Equal(t, "must be an integer value", v.Errors["year"])
+		assert.Equal(t, ErrInvalidRuntimeFormat.Error(), v.Errors["runtime"])
+
+		input = `{"title":"Black Panther","director":"Ryan Coogler"}`
+		decoder, err = NewMovieDecoder(FormatNDJSON, strings.NewReader(input))
+		assert.Nil(t, err)
+
+		v = validator.New()
+		movie, err := decoder.Decode(v)
+		assert.Nil(t, err)
+		assert.Nil(t, movie)
+		assert.Contains(t, v.Errors["row"], "unknown field")
+	})
+
+	t.Run("InvalidHeader", func(t *testing.T) {
+		for _, input := range []string{"", "title,year,runtime\n", "title,year,runtime,genres,director\n"} {
+			_, err := NewMovieDecoder(FormatCSV, strings.NewReader(input))
+			assert.ErrorIs(t, err, ErrInvalidImportHeader)
+		}
+	})
+}
+
+func TestMovieModel_Import(t *testing.T) {
+	movies := []*Movie{
+		{Title: "Black Panther", Year: 2018, Runtime: 134, Genres: []string{"action", "adventure"}},
+		{Title: "Moana", Year: 2016, Runtime: 107, Genres: []string{"animation", "adventure"}},
+	}
+
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMPORARY TABLE "MovieImports"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn := mock.ExpectPrepare(`COPY "MovieImports"`)
+	for _, movie := range movies {
+		copyIn.ExpectExec().
+			WithArgs(movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	copyIn.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO "Movies"`).WithArgs(int64(1), MovieStatusPending).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := model.Import(movies, 1, MovieStatusPending)
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
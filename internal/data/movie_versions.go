@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MovieVersion holds a snapshot of a movie's descriptive fields as they were
+// at a given version, along with the user who made the change. EditorID is nil
+// for changes that weren't made by a user, e.g. when a genre is renamed or
+// merged.
+type MovieVersion struct {
+	MovieID   int64     `json:"movie_id"`
+	Version   int32     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	EditorID  *int64    `json:"editor_id"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year,omitempty"`
+	Runtime   Runtime   `json:"runtime,omitempty"`
+	Genres    []string  `json:"genres,omitempty"`
+	// RuntimeFormat is the format that Runtime is encoded to JSON in, as in
+	// Movie.
+	RuntimeFormat RuntimeFormat `json:"-"`
+}
+
+// MarshalJSON encodes the version with its runtime in its RuntimeFormat.
+func (v MovieVersion) MarshalJSON() ([]byte, error) {
+	type movieVersionJSON MovieVersion
+
+	var runtime *FormattedRuntime
+	if v.Runtime != 0 {
+		runtime = &FormattedRuntime{Runtime: v.Runtime, Format: v.RuntimeFormat}
+	}
+
+	return json.Marshal(struct {
+		movieVersionJSON
+		Runtime *FormattedRuntime `json:"runtime,omitempty"`
+	}{movieVersionJSON(v), runtime})
+}
+
+// FieldChange holds the old and new values of a field that differs between two
+// movie versions.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// DiffMovieVersions returns the fields that differ between the from and to
+// versions, keyed by their JSON names. Runtimes are encoded in the
+// RuntimeFormat of their version.
+func DiffMovieVersions(from, to *MovieVersion) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+
+	if from.Title != to.Title {
+		changes["title"] = FieldChange{From: from.Title, To: to.Title}
+	}
+	if from.Year != to.Year {
+		changes["year"] = FieldChange{From: from.Year, To: to.Year}
+	}
+	if from.Runtime != to.Runtime {
+		changes["runtime"] = FieldChange{
+			From: FormattedRuntime{Runtime: from.Runtime, Format: from.RuntimeFormat},
+			To:   FormattedRuntime{Runtime: to.Runtime, Format: to.RuntimeFormat},
+		}
+	}
+	if !slices.Equal(from.Genres, to.Genres) {
+		changes["genres"] = FieldChange{From: from.Genres, To: to.Genres}
+	}
+
+	return changes
+}
+
+// ApplyTo copies the fields recorded in the snapshot onto the movie, as when
+// reverting it to this version. Only the descriptive fields are recorded, so
+// the movie's external identifiers and publication window are left unchanged
+// on purpose: restoring old identifiers could clash with the movies that have
+// claimed them since, and restoring an old publication window could publish
+// or unpublish the movie behind its moderators' backs. Translations are edited
+// separately and aren't part of a movie's versions either.
+func (v *MovieVersion) ApplyTo(movie *Movie) {
+	movie.Title = v.Title
+	movie.Year = v.Year
+	movie.Runtime = v.Runtime
+	movie.Genres = slices.Clone(v.Genres)
+}
+
+type MovieVersionModelInterface interface {
+	Get(movieID int64, version int32) (*MovieVersion, error)
+	GetAllForMovie(movieID int64, filters Filters) ([]*MovieVersion, Metadata, error)
+}
+
+type MovieVersionModel struct {
+	DB *sql.DB
+}
+
+func (m MovieVersionModel) Get(movieID int64, version int32) (*MovieVersion, error) {
+	if movieID < 1 || version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT movie_id, version, created_at, editor_id, title, year, runtime, genres
+		FROM "MovieVersions"
+		WHERE movie_id = $1 AND version = $2`
+
+	var movieVersion MovieVersion
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, version).Scan(
+		&movieVersion.MovieID,
+		&movieVersion.Version,
+		&movieVersion.CreatedAt,
+		&movieVersion.EditorID,
+		&movieVersion.Title,
+		&movieVersion.Year,
+		&movieVersion.Runtime,
+		pq.Array(&movieVersion.Genres),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &movieVersion, nil
+}
+
+func (m MovieVersionModel) GetAllForMovie(movieID int64, filters Filters) (
+	[]*MovieVersion, Metadata, error) {
+	query := `
+		SELECT COUNT(*) OVER(), movie_id, version, created_at, editor_id, title, year, runtime, genres
+		FROM "MovieVersions"
+		WHERE movie_id = $1
+		ORDER BY version DESC
+		LIMIT $2 OFFSET $3`
+	args := []any{
+		movieID,
+		filters.limit(),
+		filters.offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecord := 0
+	movieVersions := []*MovieVersion{}
+
+	for rows.Next() {
+		var movieVersion MovieVersion
+		err := rows.Scan(
+			&totalRecord,
+			&movieVersion.MovieID,
+			&movieVersion.Version,
+			&movieVersion.CreatedAt,
+			&movieVersion.EditorID,
+			&movieVersion.Title,
+			&movieVersion.Year,
+			&movieVersion.Runtime,
+			pq.Array(&movieVersion.Genres),
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movieVersions = append(movieVersions, &movieVersion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecord, filters.Page, filters.PageSize)
+	return movieVersions, metadata, nil
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffMovieVersions(t *testing.T) {
+	from := &MovieVersion{
+		Version: 1,
+		Title:   "Black Panther",
+		Year:    2018,
+		Runtime: 134,
+		Genres:  []string{"action", "adventure"},
+	}
+
+	t.Run("NoChanges", func(t *testing.T) {
+		to := *from
+		to.Version = 2
+		assert.Empty(t, DiffMovieVersions(from, &to))
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		to := &MovieVersion{
+			Version: 2,
+			Title:   "Black Panther",
+			Year:    2018,
+			Runtime: 135,
+			Genres:  []string{"sci-fi", "action", "adventure"},
+		}
+
+		changes := DiffMovieVersions(from, to)
+		assert.Equal(t, map[string]FieldChange{
+			"runtime": {From: FormattedRuntime{Runtime: 134}, To: FormattedRuntime{Runtime: 135}},
+			"genres": {
+				From: []string{"action", "adventure"},
+				To:   []string{"sci-fi", "action", "adventure"},
+			},
+		}, changes)
+	})
+}
+
+func TestMovieVersion_ApplyTo(t *testing.T) {
+	publishAt := time.Now().Add(-time.Hour)
+	unpublishAt := time.Now().Add(time.Hour)
+
+	movie := &Movie{
+		ID:          1,
+		Title:       "Black Panther: Wakanda Forever",
+		Year:        2022,
+		Runtime:     161,
+		Genres:      []string{"action"},
+		ExternalIDs: ExternalIDs{SourceIMDb: "tt9114286"},
+		Status:      MovieStatusPublished,
+		PublishAt:   &publishAt,
+		UnpublishAt: &unpublishAt,
+		Version:     3,
+	}
+	movieVersion := &MovieVersion{
+		MovieID: 1,
+		Version: 1,
+		Title:   "Black Panther",
+		Year:    2018,
+		Runtime: 134,
+		Genres:  []string{"action", "adventure"},
+	}
+
+	movieVersion.ApplyTo(movie)
+
+	assert.Equal(t, &Movie{
+		ID:          1,
+		Title:       "Black Panther",
+		Year:        2018,
+		Runtime:     134,
+		Genres:      []string{"action", "adventure"},
+		ExternalIDs: ExternalIDs{SourceIMDb: "tt9114286"},
+		Status:      MovieStatusPublished,
+		PublishAt:   &publishAt,
+		UnpublishAt: &unpublishAt,
+		Version:     3,
+	}, movie)
+}
+
+func TestMovieVersionModel_Get(t *testing.T) {
+	query := `
+		SELECT movie_id, version, created_at, editor_id, title, year, runtime, genres
+		FROM "MovieVersions"
+		WHERE movie_id = \$1 AND version = \$2`
+	createdAt := time.Now()
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model MovieVersionModel)
+	}{
+		{
+			name: "Success",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.
+					NewRows(
+						[]string{
+							"movie_id",
+							"version",
+							"created_at",
+							"editor_id",
+							"title",
+							"year",
+							"runtime",
+							"genres",
+						},
+					).
+					AddRow(1, 2, createdAt, 7, "Moana", 2016, 107, "{animation,adventure}")
+				mock.ExpectQuery(query).WithArgs(1, 2).WillReturnRows(rows)
+			},
+			checkModel: func(model MovieVersionModel) {
+				movieVersion, err := model.Get(1, 2)
+				assert.Nil(t, err)
+				assert.Equal(t, int32(2), movieVersion.Version)
+				assert.Equal(t, int64(7), *movieVersion.EditorID)
+				assert.Equal(t, []string{"animation", "adventure"}, movieVersion.Genres)
+			},
+		},
+		{
+			name:      "InvalidVersion",
+			buildMock: func(mock sqlmock.Sqlmock) {},
+			checkModel: func(model MovieVersionModel) {
+				movieVersion, err := model.Get(1, 0)
+				assert.Nil(t, movieVersion)
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+		{
+			name: "ErrNoRows",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1, 2).WillReturnError(sql.ErrNoRows)
+			},
+			checkModel: func(model MovieVersionModel) {
+				movieVersion, err := model.Get(1, 2)
+				assert.Nil(t, movieVersion)
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := MovieVersionModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+		})
+	}
+}
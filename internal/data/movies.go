@@ -3,8 +3,11 @@ package data
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -12,22 +15,72 @@ import (
 )
 
 type Movie struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"-"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   Runtime   `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	Version   int32     `json:"version"`
+	ID        int64      `json:"id"`
+	CreatedAt time.Time  `json:"-"`
+	Title     string     `json:"title"`
+	Year      int32      `json:"year,omitempty"`
+	Runtime   Runtime    `json:"runtime,omitempty"`
+	Genres    []string   `json:"genres,omitempty"`
+	Version   int32      `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// RatingAverage and RatingCount summarize the movie's reviews. They are
+	// kept up to date by ReviewModel, rather than edited like the fields above.
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int32   `json:"rating_count"`
+	// Poster is set through SetPoster, once the images have been stored.
+	Poster *Poster `json:"poster,omitempty"`
+	// ExternalIDs links the movie to its entries in other databases.
+	ExternalIDs ExternalIDs `json:"external_ids,omitempty"`
+	// Status is the movie's moderation status. SubmittedBy and
+	// ModerationReason are only set by Get.
+	Status           MovieStatus `json:"status,omitempty"`
+	SubmittedBy      *int64      `json:"submitted_by,omitempty"`
+	ModerationReason string      `json:"moderation_reason,omitempty"`
+	// PublishAt and UnpublishAt bound the window in which a published movie
+	// is shown to readers. Movies are created live unless PublishAt is set.
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+	// Collection is only set on single movies, by the handlers that show it.
+	Collection *CollectionSummary `json:"collection,omitempty"`
+	// The fields below are only set on movies that have been localized with a
+	// MovieTranslation.
+	OriginalTitle string `json:"original_title,omitempty"`
+	Synopsis      string `json:"synopsis,omitempty"`
+	Language      string `json:"language,omitempty"`
+	// RuntimeFormat is the format that Runtime is encoded to JSON in. It is
+	// set by the handlers to the format that the client asked for.
+	RuntimeFormat RuntimeFormat `json:"-"`
 }
 
-func ValidateMovie(v *validator.Validator, movie *Movie) {
+// MarshalJSON encodes the movie with its runtime in its RuntimeFormat.
+func (movie Movie) MarshalJSON() ([]byte, error) {
+	// movieJSON has the fields of Movie but not its methods, so that encoding
+	// it doesn't call MarshalJSON again. Its runtime is shadowed by the one
+	// below.
+	type movieJSON Movie
+
+	var runtime *FormattedRuntime
+	if movie.Runtime != 0 {
+		runtime = &FormattedRuntime{Runtime: movie.Runtime, Format: movie.RuntimeFormat}
+	}
+
+	return json.Marshal(struct {
+		movieJSON
+		Runtime *FormattedRuntime `json:"runtime,omitempty"`
+	}{movieJSON(movie), runtime})
+}
+
+// ValidateMovie checks the movie fields. Genre names and aliases known to the
+// catalogue are normalized in place to their canonical slugs before checking
+// for duplicates, and unknown genres are rejected.
+func ValidateMovie(v *validator.Validator, movie *Movie, genres GenreCatalogue) {
 	v.Check(movie.Title != "", "title", "must be provided")
 	v.Check(len(movie.Title) <= 500, "title", "must not be more than 500 bytes long")
 
 	v.Check(movie.Year != 0, "year", "must be provided")
 	v.Check(movie.Year > 1894, "year", "must be greater than 1894")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "must not be in the future")
+	// Announced movies may be released in the coming years.
+	v.Check(movie.Year <= int32(time.Now().Year())+10, "year", "must not be more than 10 years in the future")
 
 	v.Check(movie.Runtime != 0, "runtime", "must be provided")
 	v.Check(movie.Runtime > 0, "runtime", "must be a positive integer")
@@ -35,37 +88,211 @@ func ValidateMovie(v *validator.Validator, movie *Movie) {
 	v.Check(movie.Genres != nil, "genres", "must be provided")
 	v.Check(len(movie.Genres) >= 1, "genres", "must contain at least 1 genre")
 	v.Check(len(movie.Genres) <= 5, "genres", "must not contain more than 5 genres")
+	if unknown := genres.Normalize(movie.Genres); len(unknown) > 0 {
+		v.AddError("genres", fmt.Sprintf("contains unknown genres: %s", strings.Join(unknown, ", ")))
+	}
 	v.Check(validator.Unique(movie.Genres), "genres", "must not contain duplicate values")
+
+	ValidateExternalIDs(v, movie.ExternalIDs)
+
+	if movie.UnpublishAt != nil {
+		publishAt := time.Now()
+		if movie.PublishAt != nil {
+			publishAt = *movie.PublishAt
+		}
+		v.Check(movie.UnpublishAt.After(publishAt), "unpublish_at", "must be later than publish_at")
+	}
+}
+
+// MovieCriteria holds the conditions that GetAll and Export select movies by.
+// Zero values match every movie.
+type MovieCriteria struct {
+	Title        string
+	Genres       []string
+	PersonID     int64
+	ExternalIDs  ExternalIDs
+	CollectionID int64
+	Tags         []string
+	Status       MovieStatus
+	// ReleasedIn selects the movies already released in a country, or with
+	// Upcoming, the movies still to be released there. Upcoming alone selects
+	// the movies still to be released in any country.
+	ReleasedIn string
+	Upcoming   bool
+}
+
+// movieCriteriaSQL holds the WHERE conditions for MovieCriteria, using the
+// placeholders $1 to $9 for the arguments returned by MovieCriteria.args().
+// Titles are matched against the original title and against every translated
+// title and synopsis, each with the text search configuration of its language.
+// Like genres, movies must have every tag to match. Published movies only match
+// while they are live.
+const movieCriteriaSQL = `
+			deleted_at IS NULL
+			AND (
+				TO_TSVECTOR('simple', title) @@ PLAINTO_TSQUERY('simple', $1)
+				OR id IN (
+					SELECT movie_id FROM "MovieTranslations"
+					WHERE TO_TSVECTOR(search_config, title || ' ' || synopsis) @@ PLAINTO_TSQUERY(search_config, $1)
+				)
+				OR $1 = ''
+			)
+			AND (genres @> $2 OR $2 = '{}')
+			AND (id IN (SELECT movie_id FROM "Credits" WHERE person_id = $3) OR $3 = 0)
+			AND (external_ids @> $4 OR $4 = '{}')
+			AND (id IN (SELECT movie_id FROM "CollectionEntries" WHERE collection_id = $5) OR $5 = 0)
+			AND (
+				id IN (
+					SELECT mt.movie_id
+					FROM "MovieTags" mt
+					INNER JOIN "Tags" t ON t.id = mt.tag_id
+					WHERE t.name = ANY($6)
+					GROUP BY mt.movie_id
+					HAVING COUNT(*) = CARDINALITY($6)
+				)
+				OR $6 = '{}'
+			)
+			AND (status = $7 OR $7 = '')
+			AND (
+				$7 <> 'published'
+				OR (publish_at <= NOW() AND (unpublish_at IS NULL OR unpublish_at > NOW()))
+			)
+			AND (
+				id IN (
+					SELECT movie_id FROM "Releases"
+					WHERE (country = $8 OR $8 = '') AND (released_on > CURRENT_DATE) = $9
+				)
+				OR ($8 = '' AND NOT $9)
+			)`
+
+func (c MovieCriteria) args() []any {
+	genres := c.Genres
+	if genres == nil {
+		genres = []string{}
+	}
+	// Every tag must be counted once for the HAVING clause to hold.
+	tags := slices.Clone(c.Tags)
+	slices.Sort(tags)
+	tags = slices.Compact(tags)
+	if tags == nil {
+		tags = []string{}
+	}
+	return []any{c.Title, pq.Array(genres), c.PersonID, c.ExternalIDs, c.CollectionID, pq.Array(tags), c.Status, c.ReleasedIn, c.Upcoming}
 }
 
 type MovieModelInterface interface {
-	Create(movie *Movie) error
+	Create(movie *Movie, editorID int64) error
 	Get(id int64) (*Movie, error)
-	GetAll(title string, genres []string, filters Filters) ([]*Movie, Metadata, error)
-	Update(movie *Movie) error
-	Delete(id int64) error
+	GetAll(criteria MovieCriteria, filters Filters) ([]*Movie, Metadata, error)
+	Update(movie *Movie, editorID int64) error
+	Delete(id int64, version int32, editorID int64) error
+	GetAllTrashed(filters Filters) ([]*Movie, Metadata, error)
+	Restore(id int64, editorID int64) error
+	Purge(deletedBefore time.Time) (int64, []*Poster, error)
+	Import(movies []*Movie, editorID int64, status MovieStatus) error
+	Export(criteria MovieCriteria, filters Filters, fn func(*Movie) error) error
+	Transaction(fn func(movies MovieModelInterface) error) error
+	SetPoster(id int64, poster *Poster) (*Poster, error)
+	FindDuplicates(movie *Movie, live bool) ([]*Movie, error)
+	GetStats(criteria MovieCriteria) (*MovieStats, error)
+	SetStatus(movie *Movie, from MovieStatus) error
+	MarkLive() ([]*Movie, error)
 }
 
 type MovieModel struct {
 	DB *sql.DB
+	// tx is only set on the copy of the model that Transaction passes to its
+	// callback.
+	tx *sql.Tx
+}
+
+// conn returns the transaction the model is bound to, if any, or the database
+// otherwise.
+func (m MovieModel) conn() querier {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.DB
+}
+
+// Transaction calls fn with a copy of the model whose Create, Get, GetAll,
+// Update, Delete, Restore and Purge queries all run in a single transaction.
+// The transaction is committed if fn returns nil, and rolled back otherwise, in
+// which case fn's error is returned.
+func (m MovieModel) Transaction(fn func(movies MovieModelInterface) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(MovieModel{DB: m.DB, tx: tx})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m MovieModel) Create(movie *Movie) error {
+// Create inserts a new movie and records its first version in the movie's
+// revision history, attributed to the given editor, who is also recorded as
+// its submitter. Movies without a status are published, and movies without a
+// publication date are published right away.
+func (m MovieModel) Create(movie *Movie, editorID int64) error {
+	if movie.Status == "" {
+		movie.Status = MovieStatusPublished
+	}
+
 	query := `
-		INSERT INTO "Movies" (title, year, runtime, genres)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, version`
+		WITH movie AS (
+			INSERT INTO "Movies" (
+				title, year, runtime, genres, external_ids, status, submitted_by, publish_at, unpublish_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, NOW()), $9)
+			RETURNING id, created_at, title, year, runtime, genres, version, publish_at
+		), snapshot AS (
+			INSERT INTO "MovieVersions" (movie_id, version, editor_id, title, year, runtime, genres)
+			SELECT id, version, $7, title, year, runtime, genres
+			FROM movie
+		)
+		SELECT id, created_at, version, publish_at
+		FROM movie`
 	args := []any{
 		movie.Title,
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		movie.ExternalIDs,
+		movie.Status,
+		editorID,
+		movie.PublishAt,
+		movie.UnpublishAt,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.ID, &movie.CreatedAt, &movie.Version)
+	err := m.conn().QueryRowContext(ctx, query, args...).Scan(
+		&movie.ID,
+		&movie.CreatedAt,
+		&movie.Version,
+		&movie.PublishAt,
+	)
+	if err != nil {
+		switch {
+		case isDuplicateExternalID(err):
+			return ErrDuplicateExternalID
+		default:
+			return err
+		}
+	}
+
+	movie.SubmittedBy = &editorID
+
+	return nil
 }
 
 func (m MovieModel) Get(id int64) (*Movie, error) {
@@ -74,16 +301,19 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	}
 
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids, status, submitted_by, moderation_reason,
+			publish_at, unpublish_at
 		FROM "Movies"
-		WHERE id = $1`
+		WHERE id = $1 AND deleted_at IS NULL`
 
 	var movie Movie
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+	err := m.conn().QueryRowContext(ctx, query, id).Scan(
 		&movie.ID,
 		&movie.CreatedAt,
 		&movie.Title,
@@ -91,6 +321,15 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 		&movie.Runtime,
 		pq.Array(&movie.Genres),
 		&movie.Version,
+		&movie.RatingAverage,
+		&movie.RatingCount,
+		&movie.Poster,
+		&movie.ExternalIDs,
+		&movie.Status,
+		&movie.SubmittedBy,
+		&movie.ModerationReason,
+		&movie.PublishAt,
+		&movie.UnpublishAt,
 	)
 	if err != nil {
 		switch {
@@ -104,27 +343,21 @@ func (m MovieModel) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieModel) GetAll(title string, genres []string, filters Filters) (
-	[]*Movie, Metadata, error) {
+func (m MovieModel) GetAll(criteria MovieCriteria, filters Filters) ([]*Movie, Metadata, error) {
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version
+		SELECT
+			COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids, status, publish_at, unpublish_at
 		FROM "Movies"
-		WHERE
-			(TO_TSVECTOR('simple', title) @@ PLAINTO_TSQUERY('simple', $1) OR $1 = '')
-			AND (genres @> $2 OR $2 = '{}')
-		ORDER BY %s %s, id ASC
-		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
-	args := []any{
-		title,
-		pq.Array(genres),
-		filters.limit(),
-		filters.offset(),
-	}
+		WHERE %s
+		ORDER BY %s, id ASC
+		LIMIT $10 OFFSET $11`, movieCriteriaSQL, filters.orderBy(""))
+	args := append(criteria.args(), filters.limit(), filters.offset())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, args...)
+	rows, err := m.conn().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
 	}
@@ -144,6 +377,13 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) (
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
 			&movie.Version,
+			&movie.RatingAverage,
+			&movie.RatingCount,
+			&movie.Poster,
+			&movie.ExternalIDs,
+			&movie.Status,
+			&movie.PublishAt,
+			&movie.UnpublishAt,
 		)
 		if err != nil {
 			return nil, Metadata{}, err
@@ -158,29 +398,144 @@ func (m MovieModel) GetAll(title string, genres []string, filters Filters) (
 	return movies, metadata, nil
 }
 
-func (m MovieModel) Update(movie *Movie) error {
+// exportBatchSize is the number of rows fetched from the export cursor at a
+// time.
+const exportBatchSize = 1000
+
+// Export calls fn for every movie matching the criteria, in the order given by
+// filters.Sort. Pagination is ignored. The rows
+// are read through a server-side cursor in batches, so memory usage doesn't
+// depend on the size of the catalogue. If fn returns an error, the export stops
+// and the error is returned.
+func (m MovieModel) Export(criteria MovieCriteria, filters Filters, fn func(*Movie) error) error {
+	// Exports stream the whole catalogue to the client, which takes far longer
+	// than a request-scoped query.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`
+		DECLARE "MovieExport" NO SCROLL CURSOR FOR
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids
+		FROM "Movies"
+		WHERE %s
+		ORDER BY %s, id ASC`, movieCriteriaSQL, filters.orderBy(""))
+
+	_, err = tx.ExecContext(ctx, query, criteria.args()...)
+	if err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`FETCH %d FROM "MovieExport"`, exportBatchSize)
+
+	for {
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+
+		for rows.Next() {
+			var movie Movie
+			err := rows.Scan(
+				&movie.ID,
+				&movie.CreatedAt,
+				&movie.Title,
+				&movie.Year,
+				&movie.Runtime,
+				pq.Array(&movie.Genres),
+				&movie.Version,
+				&movie.RatingAverage,
+				&movie.RatingCount,
+				&movie.Poster,
+				&movie.ExternalIDs,
+			)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			err = fn(&movie)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			fetched++
+		}
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if fetched < exportBatchSize {
+			break
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Update saves the movie if it hasn't been changed since it was read, and
+// records the new version in the movie's revision history, attributed to the
+// given editor. As in Create, movies without a publication date, such as
+// those whose date has been cleared, are published right away, and movies
+// rescheduled to go live in the future will be announced again by MarkLive.
+//
+// The only status change that Update makes is sending a published movie back
+// to the moderation queue, when the movie's status has been set to pending;
+// other changes go through SetStatus. Since the check is made against the
+// status in the database, an edit never undoes a rejection made in the
+// meantime.
+func (m MovieModel) Update(movie *Movie, editorID int64) error {
 	query := `
-		UPDATE "Movies"
-		SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-		WHERE id = $5 AND version = $6
-		RETURNING version`
+		WITH movie AS (
+			UPDATE "Movies"
+			SET
+				title = $1, year = $2, runtime = $3, genres = $4, external_ids = $5,
+				publish_at = COALESCE($9, NOW()), unpublish_at = $10,
+				went_live_at = CASE WHEN $9 > NOW() THEN NULL ELSE went_live_at END,
+				status = CASE WHEN status = 'published' AND $11 = 'pending' THEN 'pending' ELSE status END,
+				version = version + 1
+			WHERE id = $6 AND version = $7 AND deleted_at IS NULL
+			RETURNING id, title, year, runtime, genres, version, publish_at, status
+		), snapshot AS (
+			INSERT INTO "MovieVersions" (movie_id, version, editor_id, title, year, runtime, genres)
+			SELECT id, version, $8, title, year, runtime, genres
+			FROM movie
+		)
+		SELECT version, publish_at, status
+		FROM movie`
 	args := []any{
 		movie.Title,
 		movie.Year,
 		movie.Runtime,
 		pq.Array(movie.Genres),
+		movie.ExternalIDs,
 		movie.ID,
 		movie.Version,
+		editorID,
+		movie.PublishAt,
+		movie.UnpublishAt,
+		movie.Status,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&movie.Version)
+	err := m.conn().QueryRowContext(ctx, query, args...).Scan(&movie.Version, &movie.PublishAt, &movie.Status)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
+		case isDuplicateExternalID(err):
+			return ErrDuplicateExternalID
 		default:
 			return err
 		}
@@ -189,19 +544,125 @@ func (m MovieModel) Update(movie *Movie) error {
 	return nil
 }
 
-func (m MovieModel) Delete(id int64) error {
+// Delete moves a movie to the trash if it hasn't been changed since it was
+// read. Trashed movies are hidden from every other read path until they are
+// either restored or purged. Like an edit, it increments the movie's version
+// and records it in the movie's history, so that the movie's ETag changes.
+func (m MovieModel) Delete(id int64, version int32, editorID int64) error {
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 
 	query := `
-		DELETE FROM "Movies"
-		WHERE id = $1`
+		WITH movie AS (
+			UPDATE "Movies"
+			SET deleted_at = NOW(), version = version + 1
+			WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+			RETURNING id, title, year, runtime, genres, version
+		)
+		INSERT INTO "MovieVersions" (movie_id, version, editor_id, title, year, runtime, genres)
+		SELECT id, version, $3, title, year, runtime, genres
+		FROM movie`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.conn().ExecContext(ctx, query, id, version, editorID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrEditConflict
+	}
+
+	return nil
+}
+
+// GetAllTrashed returns a page of the movies that are in the trash.
+func (m MovieModel) GetAllTrashed(filters Filters) ([]*Movie, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(), id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids, deleted_at
+		FROM "Movies"
+		WHERE deleted_at IS NOT NULL
+		ORDER BY %s, id ASC
+		LIMIT $1 OFFSET $2`, filters.orderBy(""))
+	args := []any{
+		filters.limit(),
+		filters.offset(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecord := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		var movie Movie
+		err := rows.Scan(
+			&totalRecord,
+			&movie.ID,
+			&movie.CreatedAt,
+			&movie.Title,
+			&movie.Year,
+			&movie.Runtime,
+			pq.Array(&movie.Genres),
+			&movie.Version,
+			&movie.RatingAverage,
+			&movie.RatingCount,
+			&movie.Poster,
+			&movie.ExternalIDs,
+			&movie.DeletedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		movies = append(movies, &movie)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecord, filters.Page, filters.PageSize)
+	return movies, metadata, nil
+}
+
+// Restore takes a movie out of the trash. Like Delete, it increments the
+// movie's version and records it in the movie's history.
+func (m MovieModel) Restore(id int64, editorID int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `
+		WITH movie AS (
+			UPDATE "Movies"
+			SET deleted_at = NULL, version = version + 1
+			WHERE id = $1 AND deleted_at IS NOT NULL
+			RETURNING id, title, year, runtime, genres, version
+		)
+		INSERT INTO "MovieVersions" (movie_id, version, editor_id, title, year, runtime, genres)
+		SELECT id, version, $2, title, year, runtime, genres
+		FROM movie`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.conn().ExecContext(ctx, query, id, editorID)
 	if err != nil {
 		return err
 	}
@@ -217,3 +678,118 @@ func (m MovieModel) Delete(id int64) error {
 
 	return nil
 }
+
+// Purge permanently deletes the movies that were moved to the trash before the
+// given time, and returns the number of deleted movies along with their
+// posters, whose images are left for the caller to delete.
+func (m MovieModel) Purge(deletedBefore time.Time) (int64, []*Poster, error) {
+	query := `
+		DELETE FROM "Movies"
+		WHERE deleted_at < $1
+		RETURNING poster`
+
+	// Purging runs in the background and may touch many rows, so it gets a more
+	// generous timeout than the request-scoped queries.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := m.conn().QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var count int64
+	posters := []*Poster{}
+
+	for rows.Next() {
+		var poster *Poster
+		err := rows.Scan(&poster)
+		if err != nil {
+			return 0, nil, err
+		}
+		count++
+		if poster != nil {
+			posters = append(posters, poster)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return count, posters, nil
+}
+
+// Import inserts a batch of movies in a single transaction and records the
+// first version of each of them, attributed to the given editor, who is also
+// recorded as their submitter. Every movie gets the given status. The rows are
+// streamed with COPY into a temporary table first, which is much faster than
+// inserting them one by one, and then moved into the "Movies" table with a
+// single statement.
+func (m MovieModel) Import(movies []*Movie, editorID int64, status MovieStatus) error {
+	// Importing a batch takes longer than a request-scoped query.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		CREATE TEMPORARY TABLE "MovieImports" (
+			title TEXT NOT NULL,
+			year INTEGER NOT NULL,
+			runtime INTEGER NOT NULL,
+			genres TEXT[] NOT NULL
+		) ON COMMIT DROP`
+
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("MovieImports", "title", "year", "runtime", "genres"))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, movie := range movies {
+		_, err = stmt.ExecContext(ctx, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Flush the buffered rows, and close the statement to end the COPY before
+	// running anything else in the transaction.
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	query = `
+		WITH movie AS (
+			INSERT INTO "Movies" (title, year, runtime, genres, status, submitted_by)
+			SELECT title, year, runtime, genres, $2, $1
+			FROM "MovieImports"
+			RETURNING id, title, year, runtime, genres, version
+		)
+		INSERT INTO "MovieVersions" (movie_id, version, editor_id, title, year, runtime, genres)
+		SELECT id, version, $1, title, year, runtime, genres
+		FROM movie`
+
+	_, err = tx.ExecContext(ctx, query, editorID, status)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
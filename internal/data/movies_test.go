@@ -2,6 +2,7 @@ package data
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -12,6 +13,12 @@ import (
 )
 
 func TestValidateMovie(t *testing.T) {
+	genres := NewGenreCatalogue([]*Genre{
+		{Slug: "action"},
+		{Slug: "thriller"},
+		{Slug: "sci-fi", Aliases: []string{"science fiction"}},
+	})
+
 	t.Run("InvalidMovie", func(t *testing.T) {
 		movie := &Movie{
 			Title:   "",         // Invalid: empty title
@@ -21,7 +28,7 @@ func TestValidateMovie(t *testing.T) {
 		}
 
 		v := validator.New()
-		ValidateMovie(v, movie)
+		ValidateMovie(v, movie, genres)
 		assert.False(t, v.Valid())
 
 		expectedErrors := map[string]string{
@@ -45,8 +52,71 @@ func TestValidateMovie(t *testing.T) {
 		}
 
 		v := validator.New()
-		ValidateMovie(v, movie)
+		ValidateMovie(v, movie, genres)
 		assert.True(t, v.Valid())
+		assert.Equal(t, []string{"action", "thriller"}, movie.Genres)
+	})
+
+	t.Run("UnknownGenre", func(t *testing.T) {
+		movie := &Movie{
+			Title:   "Test Movie",
+			Year:    2023,
+			Runtime: 120,
+			Genres:  []string{"Action", "Western"},
+		}
+
+		v := validator.New()
+		ValidateMovie(v, movie, genres)
+		assert.False(t, v.Valid())
+		assert.Equal(t, "contains unknown genres: Western", v.Errors["genres"])
+	})
+
+	t.Run("DuplicateAlias", func(t *testing.T) {
+		movie := &Movie{
+			Title:   "Test Movie",
+			Year:    2023,
+			Runtime: 120,
+			Genres:  []string{"Sci-Fi", "science fiction"},
+		}
+
+		v := validator.New()
+		ValidateMovie(v, movie, genres)
+		assert.False(t, v.Valid())
+		assert.Equal(t, "must not contain duplicate values", v.Errors["genres"])
+	})
+
+	t.Run("UnpublishBeforePublish", func(t *testing.T) {
+		publishAt := time.Now().Add(24 * time.Hour)
+		unpublishAt := publishAt.Add(-time.Hour)
+		movie := &Movie{
+			Title:       "Test Movie",
+			Year:        2023,
+			Runtime:     120,
+			Genres:      []string{"Action"},
+			PublishAt:   &publishAt,
+			UnpublishAt: &unpublishAt,
+		}
+
+		v := validator.New()
+		ValidateMovie(v, movie, genres)
+		assert.False(t, v.Valid())
+		assert.Equal(t, "must be later than publish_at", v.Errors["unpublish_at"])
+	})
+
+	t.Run("UnpublishInPast", func(t *testing.T) {
+		unpublishAt := time.Now().Add(-time.Hour)
+		movie := &Movie{
+			Title:       "Test Movie",
+			Year:        2023,
+			Runtime:     120,
+			Genres:      []string{"Action"},
+			UnpublishAt: &unpublishAt,
+		}
+
+		v := validator.New()
+		ValidateMovie(v, movie, genres)
+		assert.False(t, v.Valid())
+		assert.Equal(t, "must be later than publish_at", v.Errors["unpublish_at"])
 	})
 }
 
@@ -58,18 +128,32 @@ func NewMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 
 func TestMovieModel_Create(t *testing.T) {
 	query := `
-		INSERT INTO Movies \(title, year, runtime, genres\)
-		VALUES \(\$1, \$2, \$3, \$4\)
-		RETURNING id, created_at, version`
+		WITH movie AS \(
+			INSERT INTO "Movies" \(
+				title, year, runtime, genres, external_ids, status, submitted_by, publish_at, unpublish_at
+			\)
+			VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, COALESCE\(\$8, NOW\(\)\), \$9\)
+			RETURNING id, created_at, title, year, runtime, genres, version, publish_at
+		\), snapshot AS \(
+			INSERT INTO "MovieVersions" \(movie_id, version, editor_id, title, year, runtime, genres\)
+			SELECT id, version, \$7, title, year, runtime, genres
+			FROM movie
+		\)
+		SELECT id, created_at, version, publish_at
+		FROM movie`
 	createdAt := time.Now()
+	publishAt := time.Date(2023, time.June, 16, 0, 0, 0, 0, time.UTC)
 	movie := &Movie{
-		ID:        1,
-		CreatedAt: createdAt,
-		Title:     "Asteroid City",
-		Year:      2023,
-		Runtime:   105,
-		Genres:    []string{"Comedy", "Romance"},
-		Version:   1,
+		ID:          1,
+		CreatedAt:   createdAt,
+		Title:       "Asteroid City",
+		Year:        2023,
+		Runtime:     105,
+		Genres:      []string{"Comedy", "Romance"},
+		ExternalIDs: ExternalIDs{"imdb": "tt14230388"},
+		Status:      MovieStatusPending,
+		PublishAt:   &publishAt,
+		Version:     1,
 	}
 
 	tests := []struct {
@@ -81,26 +165,39 @@ func TestMovieModel_Create(t *testing.T) {
 			name: "Success",
 			buildMock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows(
-					[]string{"id", "created_at", "version"}).
-					AddRow(1, createdAt, 1)
+					[]string{"id", "created_at", "version", "publish_at"}).
+					AddRow(1, createdAt, 1, publishAt)
 				mock.ExpectQuery(query).
-					WithArgs("Asteroid City", 2023, 105, pq.Array([]string{"Comedy", "Romance"})).
+					WithArgs("Asteroid City", 2023, 105, pq.Array([]string{"Comedy", "Romance"}), []byte(`{"imdb":"tt14230388"}`), MovieStatusPending, 7, publishAt, nil).
 					WillReturnRows(rows)
 			},
 			checkModel: func(model MovieModel) {
-				err := model.Create(movie)
+				err := model.Create(movie, 7)
 				assert.Nil(t, err)
+				assert.Equal(t, publishAt, *movie.PublishAt)
+			},
+		},
+		{
+			name: "ErrDuplicateExternalID",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).
+					WithArgs("Asteroid City", 2023, 105, pq.Array([]string{"Comedy", "Romance"}), []byte(`{"imdb":"tt14230388"}`), MovieStatusPending, 7, publishAt, nil).
+					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "movies_external_ids_imdb_index"`))
+			},
+			checkModel: func(model MovieModel) {
+				err := model.Create(movie, 7)
+				assert.Equal(t, ErrDuplicateExternalID, err)
 			},
 		},
 		{
 			name: "ErrConnDone",
 			buildMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(query).
-					WithArgs("Asteroid City", 2023, 105, pq.Array([]string{"Comedy", "Romance"})).
+					WithArgs("Asteroid City", 2023, 105, pq.Array([]string{"Comedy", "Romance"}), []byte(`{"imdb":"tt14230388"}`), MovieStatusPending, 7, publishAt, nil).
 					WillReturnError(sql.ErrConnDone)
 			},
 			checkModel: func(model MovieModel) {
-				err := model.Create(movie)
+				err := model.Create(movie, 7)
 				assert.Equal(t, sql.ErrConnDone, err)
 			},
 		},
@@ -119,9 +216,12 @@ func TestMovieModel_Create(t *testing.T) {
 
 func TestMovieModel_Get(t *testing.T) {
 	query := `
-		SELECT id, created_at, title, year, runtime, genres, version
-		FROM Movies
-		WHERE id = \$1`
+		SELECT
+			id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids, status, submitted_by, moderation_reason,
+			publish_at, unpublish_at
+		FROM "Movies"
+		WHERE id = \$1 AND deleted_at IS NULL`
 	createdAt := time.Now()
 
 	tests := []struct {
@@ -142,9 +242,22 @@ func TestMovieModel_Get(t *testing.T) {
 							"runtime",
 							"genres",
 							"version",
+							"rating_average",
+							"rating_count",
+							"poster",
+							"external_ids",
+							"status",
+							"submitted_by",
+							"moderation_reason",
+							"publish_at",
+							"unpublish_at",
 						},
 					).
-					AddRow(1, createdAt, "Test Movie 1", 2022, 120, "{Comedy,Romance}", 1)
+					AddRow(
+						1, createdAt, "Test Movie 1", 2022, 120, "{Comedy,Romance}", 1, "7.50", 2,
+						`{"key":"posters/1/original.png","url":"http://localhost:4000/uploads/posters/1/original.png"}`,
+						`{"imdb":"tt0000001"}`, "pending", 7, "", createdAt, nil,
+					)
 				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
 			},
 			checkModel: func(model MovieModel) {
@@ -158,6 +271,14 @@ func TestMovieModel_Get(t *testing.T) {
 				assert.Equal(t, int32(120), int32(movie.Runtime))
 				assert.Equal(t, []string{"Comedy", "Romance"}, movie.Genres)
 				assert.Equal(t, int32(1), movie.Version)
+				assert.Equal(t, 7.5, movie.RatingAverage)
+				assert.Equal(t, int32(2), movie.RatingCount)
+				assert.Equal(t, "posters/1/original.png", movie.Poster.Key)
+				assert.Equal(t, ExternalIDs{"imdb": "tt0000001"}, movie.ExternalIDs)
+				assert.Equal(t, MovieStatusPending, movie.Status)
+				assert.Equal(t, int64(7), *movie.SubmittedBy)
+				assert.Equal(t, createdAt, *movie.PublishAt)
+				assert.Nil(t, movie.UnpublishAt)
 			},
 		},
 		{
@@ -206,13 +327,49 @@ func TestMovieModel_Get(t *testing.T) {
 
 func TestMovieModel_GetAll(t *testing.T) {
 	query := `
-		SELECT COUNT\(\*\) OVER\(\), id, created_at, title, year, runtime, genres, version
-		FROM Movies
+		SELECT
+			COUNT\(\*\) OVER\(\), id, created_at, title, year, runtime, genres, version,
+			rating_average, rating_count, poster, external_ids, status, publish_at, unpublish_at
+		FROM "Movies"
 		WHERE
-			\(TO_TSVECTOR\('simple', title\) @@ PLAINTO_TSQUERY\('simple', \$1\) OR \$1 = ''\)
+			deleted_at IS NULL
+			AND \(
+				TO_TSVECTOR\('simple', title\) @@ PLAINTO_TSQUERY\('simple', \$1\)
+				OR id IN \(
+					SELECT movie_id FROM "MovieTranslations"
+					WHERE TO_TSVECTOR\(search_config, title \|\| ' ' \|\| synopsis\) @@ PLAINTO_TSQUERY\(search_config, \$1\)
+				\)
+				OR \$1 = ''
+			\)
 			AND \(genres @> \$2 OR \$2 = '{}'\)
+			AND \(id IN \(SELECT movie_id FROM "Credits" WHERE person_id = \$3\) OR \$3 = 0\)
+			AND \(external_ids @> \$4 OR \$4 = '{}'\)
+			AND \(id IN \(SELECT movie_id FROM "CollectionEntries" WHERE collection_id = \$5\) OR \$5 = 0\)
+			AND \(
+				id IN \(
+					SELECT mt.movie_id
+					FROM "MovieTags" mt
+					INNER JOIN "Tags" t ON t.id = mt.tag_id
+					WHERE t.name = ANY\(\$6\)
+					GROUP BY mt.movie_id
+					HAVING COUNT\(\*\) = CARDINALITY\(\$6\)
+				\)
+				OR \$6 = '{}'
+			\)
+			AND \(status = \$7 OR \$7 = ''\)
+			AND \(
+				\$7 <> 'published'
+				OR \(publish_at <= NOW\(\) AND \(unpublish_at IS NULL OR unpublish_at > NOW\(\)\)\)
+			\)
+			AND \(
+				id IN \(
+					SELECT movie_id FROM "Releases"
+					WHERE \(country = \$8 OR \$8 = ''\) AND \(released_on > CURRENT_DATE\) = \$9
+				\)
+				OR \(\$8 = '' AND NOT \$9\)
+			\)
 		ORDER BY title DESC, id ASC
-		LIMIT \$3 OFFSET \$4`
+		LIMIT \$10 OFFSET \$11`
 	createdAt := time.Now()
 	filters := Filters{
 		Page:           1,
@@ -240,16 +397,23 @@ func TestMovieModel_GetAll(t *testing.T) {
 							"runtime",
 							"genres",
 							"version",
+							"rating_average",
+							"rating_count",
+							"poster",
+							"external_ids",
+							"status",
+							"publish_at",
+							"unpublish_at",
 						},
 					).
-					AddRow(2, 2, createdAt, "Test Funny Movie", 2022, 99, "{}", 1).
-					AddRow(2, 1, createdAt, "Test Boring Movie", 2020, 99, "{}", 1)
+					AddRow(2, 2, createdAt, "Test Funny Movie", 2022, 99, "{}", 1, "0.00", 0, nil, "{}", "published", createdAt, nil).
+					AddRow(2, 1, createdAt, "Test Boring Movie", 2020, 99, "{}", 1, "0.00", 0, nil, "{}", "published", createdAt, nil)
 				mock.ExpectQuery(query).
-					WithArgs("Movie", pq.Array([]string{}), 20, 0).
+					WithArgs("Movie", pq.Array([]string{}), 0, []byte("{}"), 0, pq.Array([]string{}), MovieStatusPublished, "", false, 20, 0).
 					WillReturnRows(rows)
 			},
 			checkModel: func(model MovieModel) {
-				movies, metadata, err := model.GetAll("Movie", []string{}, filters)
+				movies, metadata, err := model.GetAll(MovieCriteria{Title: "Movie", Status: MovieStatusPublished}, filters)
 				assert.Nil(t, err)
 				assert.NotNil(t, movies)
 				assert.NotNil(t, metadata)
@@ -262,11 +426,11 @@ func TestMovieModel_GetAll(t *testing.T) {
 			name: "ErrConnDone",
 			buildMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(query).
-					WithArgs("Movie", pq.Array([]string{}), 20, 0).
+					WithArgs("Movie", pq.Array([]string{}), 0, []byte("{}"), 0, pq.Array([]string{}), MovieStatusPublished, "", false, 20, 0).
 					WillReturnError(sql.ErrConnDone)
 			},
 			checkModel: func(model MovieModel) {
-				movies, metadata, err := model.GetAll("Movie", []string{}, filters)
+				movies, metadata, err := model.GetAll(MovieCriteria{Title: "Movie", Status: MovieStatusPublished}, filters)
 				assert.Nil(t, movies)
 				assert.Equal(t, Metadata{}, metadata)
 				assert.Equal(t, sql.ErrConnDone, err)
@@ -287,10 +451,23 @@ func TestMovieModel_GetAll(t *testing.T) {
 
 func TestMovielModel_Update(t *testing.T) {
 	query := `
-		UPDATE Movies
-		SET title = \$1, year = \$2, runtime = \$3, genres = \$4, version = version \+ 1
-		WHERE id = \$5 AND version = \$6
-		RETURNING version`
+		WITH movie AS \(
+			UPDATE "Movies"
+			SET
+				title = \$1, year = \$2, runtime = \$3, genres = \$4, external_ids = \$5,
+				publish_at = COALESCE\(\$9, NOW\(\)\), unpublish_at = \$10,
+				went_live_at = CASE WHEN \$9 > NOW\(\) THEN NULL ELSE went_live_at END,
+				status = CASE WHEN status = 'published' AND \$11 = 'pending' THEN 'pending' ELSE status END,
+				version = version \+ 1
+			WHERE id = \$6 AND version = \$7 AND deleted_at IS NULL
+			RETURNING id, title, year, runtime, genres, version, publish_at, status
+		\), snapshot AS \(
+			INSERT INTO "MovieVersions" \(movie_id, version, editor_id, title, year, runtime, genres\)
+			SELECT id, version, \$8, title, year, runtime, genres
+			FROM movie
+		\)
+		SELECT version, publish_at, status
+		FROM movie`
 	createdAt := time.Now()
 
 	tests := []struct {
@@ -301,9 +478,9 @@ func TestMovielModel_Update(t *testing.T) {
 		{
 			name: "UpdateTitle",
 			buildMock: func(mock sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"version"}).AddRow(2)
+				rows := sqlmock.NewRows([]string{"version", "publish_at", "status"}).AddRow(2, createdAt, "pending")
 				mock.ExpectQuery(query).
-					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), 1, 1).
+					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), []byte("{}"), 1, 1, 7, nil, nil, MovieStatusPending).
 					WillReturnRows(rows)
 			},
 			checkModel: func(model MovieModel) {
@@ -315,17 +492,20 @@ func TestMovielModel_Update(t *testing.T) {
 					Runtime:   99,
 					Genres:    []string{"Sci-fi"},
 					Version:   1,
+					Status:    MovieStatusPending,
 				}
-				err := model.Update(movie)
+				err := model.Update(movie, 7)
 				assert.Nil(t, err)
 				assert.Equal(t, int32(2), movie.Version)
+				assert.Equal(t, createdAt, *movie.PublishAt)
+				assert.Equal(t, MovieStatusPending, movie.Status)
 			},
 		},
 		{
 			name: "ErrNoRows",
 			buildMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(query).
-					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), 1, 1).
+					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), []byte("{}"), 1, 1, 7, nil, nil, MovieStatusPending).
 					WillReturnError(sql.ErrNoRows)
 			},
 			checkModel: func(model MovieModel) {
@@ -337,8 +517,9 @@ func TestMovielModel_Update(t *testing.T) {
 					Runtime:   99,
 					Genres:    []string{"Sci-fi"},
 					Version:   1,
+					Status:    MovieStatusPending,
 				}
-				err := model.Update(movie)
+				err := model.Update(movie, 7)
 				assert.Equal(t, ErrEditConflict, err)
 			},
 		},
@@ -346,7 +527,7 @@ func TestMovielModel_Update(t *testing.T) {
 			name: "ErrConnDone",
 			buildMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(query).
-					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), 1, 1).
+					WithArgs("Updated Movie", 2022, 99, pq.Array([]string{"Sci-fi"}), []byte("{}"), 1, 1, 7, nil, nil, MovieStatusPending).
 					WillReturnError(sql.ErrConnDone)
 			},
 			checkModel: func(model MovieModel) {
@@ -358,8 +539,9 @@ func TestMovielModel_Update(t *testing.T) {
 					Runtime:   99,
 					Genres:    []string{"Sci-fi"},
 					Version:   1,
+					Status:    MovieStatusPending,
 				}
-				err := model.Update(movie)
+				err := model.Update(movie, 7)
 				assert.Equal(t, sql.ErrConnDone, err)
 			},
 		},
@@ -378,8 +560,15 @@ func TestMovielModel_Update(t *testing.T) {
 
 func TestMovielModel_Delete(t *testing.T) {
 	query := `
-		DELETE FROM Movies
-		WHERE id = \$1`
+		WITH movie AS \(
+			UPDATE "Movies"
+			SET deleted_at = NOW\(\), version = version \+ 1
+			WHERE id = \$1 AND version = \$2 AND deleted_at IS NULL
+			RETURNING id, title, year, runtime, genres, version
+		\)
+		INSERT INTO "MovieVersions" \(movie_id, version, editor_id, title, year, runtime, genres\)
+		SELECT id, version, \$3, title, year, runtime, genres
+		FROM movie`
 
 	tests := []struct {
 		name       string
@@ -390,37 +579,37 @@ func TestMovielModel_Delete(t *testing.T) {
 			name:      "InvalidID",
 			buildMock: func(mock sqlmock.Sqlmock) {},
 			checkModel: func(model MovieModel) {
-				err := model.Delete(0)
+				err := model.Delete(0, 1, 1)
 				assert.Equal(t, ErrRecordNotFound, err)
 			},
 		},
 		{
-			name: "ErrRecordNotFound",
+			name: "ErrEditConflict",
 			buildMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 0))
+				mock.ExpectExec(query).WithArgs(1, 1, 1).WillReturnResult(sqlmock.NewResult(1, 0))
 			},
 			checkModel: func(model MovieModel) {
-				err := model.Delete(1)
-				assert.Equal(t, ErrRecordNotFound, err)
+				err := model.Delete(1, 1, 1)
+				assert.Equal(t, ErrEditConflict, err)
 			},
 		},
 		{
 			name: "ErrConnDone",
 			buildMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(query).WithArgs(1).WillReturnError(sql.ErrConnDone)
+				mock.ExpectExec(query).WithArgs(1, 1, 1).WillReturnError(sql.ErrConnDone)
 			},
 			checkModel: func(model MovieModel) {
-				err := model.Delete(1)
+				err := model.Delete(1, 1, 1)
 				assert.Equal(t, sql.ErrConnDone, err)
 			},
 		},
 		{
 			name: "Success",
 			buildMock: func(mock sqlmock.Sqlmock) {
-				mock.ExpectExec(query).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(query).WithArgs(1, 1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 			checkModel: func(model MovieModel) {
-				err := model.Delete(1)
+				err := model.Delete(1, 1, 1)
 				assert.Nil(t, err)
 			},
 		},
@@ -436,3 +625,141 @@ func TestMovielModel_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestMovieModel_Restore(t *testing.T) {
+	query := `
+		WITH movie AS \(
+			UPDATE "Movies"
+			SET deleted_at = NULL, version = version \+ 1
+			WHERE id = \$1 AND deleted_at IS NOT NULL
+			RETURNING id, title, year, runtime, genres, version
+		\)
+		INSERT INTO "MovieVersions" \(movie_id, version, editor_id, title, year, runtime, genres\)
+		SELECT id, version, \$2, title, year, runtime, genres
+		FROM movie`
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model MovieModel)
+	}{
+		{
+			name: "NotInTrash",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(1, 0))
+			},
+			checkModel: func(model MovieModel) {
+				err := model.Restore(1, 1)
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+		{
+			name: "Success",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(query).WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+			checkModel: func(model MovieModel) {
+				err := model.Restore(1, 1)
+				assert.Nil(t, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := MovieModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+		})
+	}
+}
+
+func TestMovieModel_Purge(t *testing.T) {
+	query := `
+		DELETE FROM "Movies"
+		WHERE deleted_at < \$1
+		RETURNING poster`
+	deletedBefore := time.Now()
+
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{"poster"}).
+		AddRow(nil).
+		AddRow(`{"key":"posters/2/original.png","thumbnails":{"small":{"key":"posters/2/small.jpg"}}}`).
+		AddRow(nil)
+	mock.ExpectQuery(query).WithArgs(deletedBefore).WillReturnRows(rows)
+
+	count, posters, err := model.Purge(deletedBefore)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+	assert.Len(t, posters, 1)
+	assert.ElementsMatch(t, []string{"posters/2/original.png", "posters/2/small.jpg"}, posters[0].Keys())
+}
+
+func TestMovieModel_Transaction(t *testing.T) {
+	query := `
+		WITH movie AS \(
+			UPDATE "Movies"
+			SET deleted_at = NOW\(\), version = version \+ 1
+			WHERE id = \$1 AND version = \$2 AND deleted_at IS NULL`
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model MovieModel)
+	}{
+		{
+			name: "Commit",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(1, 1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(query).WithArgs(2, 3, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model MovieModel) {
+				err := model.Transaction(func(movies MovieModelInterface) error {
+					err := movies.Delete(1, 1, 1)
+					if err != nil {
+						return err
+					}
+					return movies.Delete(2, 3, 1)
+				})
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "Rollback",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(query).WithArgs(1, 1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(query).WithArgs(2, 3, 1).WillReturnResult(sqlmock.NewResult(1, 0))
+				mock.ExpectRollback()
+			},
+			checkModel: func(model MovieModel) {
+				err := model.Transaction(func(movies MovieModelInterface) error {
+					err := movies.Delete(1, 1, 1)
+					if err != nil {
+						return err
+					}
+					return movies.Delete(2, 3, 1)
+				})
+				assert.Equal(t, ErrEditConflict, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := MovieModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
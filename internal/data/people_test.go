@@ -0,0 +1,108 @@
+package data
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidatePerson(t *testing.T) {
+	birthYear := int32(1986)
+	futureYear := int32(time.Now().Year() + 1)
+
+	tests := []struct {
+		name     string
+		person   *Person
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			person:   &Person{Name: "Ryan Coogler", BirthYear: &birthYear},
+			expected: map[string]string{},
+		},
+		{
+			name:     "UnknownBirthYear",
+			person:   &Person{Name: "Ryan Coogler"},
+			expected: map[string]string{},
+		},
+		{
+			name:   "Invalid",
+			person: &Person{BirthYear: &futureYear},
+			expected: map[string]string{
+				"name":       "must be provided",
+				"birth_year": "must not be in the future",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidatePerson(v, test.person)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestPersonModel_Get(t *testing.T) {
+	query := `
+		SELECT id, created_at, name, birth_year, bio, version
+		FROM "People"
+		WHERE id = \$1`
+	createdAt := time.Now()
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model PersonModel)
+	}{
+		{
+			name:      "InvalidID",
+			buildMock: func(mock sqlmock.Sqlmock) {},
+			checkModel: func(model PersonModel) {
+				person, err := model.Get(0)
+				assert.Nil(t, person)
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+		{
+			name: "NullBirthYear",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "created_at", "name", "birth_year", "bio", "version"}).
+					AddRow(1, createdAt, "Ryan Coogler", nil, "", 1)
+				mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows)
+			},
+			checkModel: func(model PersonModel) {
+				person, err := model.Get(1)
+				assert.Nil(t, err)
+				assert.Equal(t, "Ryan Coogler", person.Name)
+				assert.Nil(t, person.BirthYear)
+			},
+		},
+		{
+			name: "ErrRecordNotFound",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			checkModel: func(model PersonModel) {
+				person, err := model.Get(1)
+				assert.Nil(t, person)
+				assert.Equal(t, ErrRecordNotFound, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := PersonModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+		})
+	}
+}
@@ -17,10 +17,10 @@ func TestPermissionsInclude(t *testing.T) {
 
 func TestPermissionMovel_AddForUser(t *testing.T) {
 	query := `
-		INSERT INTO UsersPermissions
-		SELECT \$1, permissions\.id
-		FROM Permissions
-		WHERE Permissions.code = ANY\(\$2\)`
+		INSERT INTO "UsersPermissions"
+		SELECT \$1, "Permissions"\.id
+		FROM "Permissions"
+		WHERE "Permissions".code = ANY\(\$2\)`
 
 	tests := []struct {
 		name       string
@@ -66,13 +66,13 @@ func TestPermissionMovel_AddForUser(t *testing.T) {
 
 func TestPermissionMovel_GetAllForUser(t *testing.T) {
 	query := `
-		SELECT Permissions\.code
-		FROM Permissions
-		INNER JOIN UsersPermissions
-			ON \(UsersPermissions\.permission_id = Permissions\.id\)
-		INNER JOIN Users
-			ON \(UsersPermissions\.user_id = Users\.id\)
-		WHERE Users\.id = \$1`
+		SELECT "Permissions"\.code
+		FROM "Permissions"
+		INNER JOIN "UsersPermissions"
+			ON \("UsersPermissions"\.permission_id = "Permissions"\.id\)
+		INNER JOIN "Users"
+			ON \("UsersPermissions"\.user_id = "Users"\.id\)
+		WHERE "Users"\.id = \$1`
 
 	tests := []struct {
 		name       string
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Image describes an image file stored in a storage.BlobStore.
+type Image struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+// Poster describes a movie's poster art: the image as uploaded and the
+// thumbnails generated from it, keyed by their size name.
+type Poster struct {
+	Image
+	Thumbnails map[string]Image `json:"thumbnails,omitempty"`
+}
+
+// Keys returns the keys of the poster's image and all its thumbnails.
+func (p *Poster) Keys() []string {
+	keys := []string{p.Key}
+	for _, thumbnail := range p.Thumbnails {
+		keys = append(keys, thumbnail.Key)
+	}
+	return keys
+}
+
+// Value stores the poster in a JSONB column.
+func (p Poster) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan reads the poster from a JSONB column.
+func (p *Poster) Scan(src any) error {
+	switch src := src.(type) {
+	case []byte:
+		return json.Unmarshal(src, p)
+	case string:
+		return json.Unmarshal([]byte(src), p)
+	default:
+		return errors.New("unsupported poster column type")
+	}
+}
+
+// SetPoster replaces the poster of a movie, or removes it if poster is nil, and
+// returns the poster it replaced, so that its images can be deleted. Like the
+// rating summary, the poster is not versioned, so the movie's version is left
+// unchanged.
+func (m MovieModel) SetPoster(id int64, poster *Poster) (*Poster, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var old *Poster
+
+	query := `
+		SELECT poster
+		FROM "Movies"
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(&old)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE "Movies"
+		SET poster = $1
+		WHERE id = $2`
+
+	_, err = tx.ExecContext(ctx, query, poster, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return old, nil
+}
@@ -0,0 +1,193 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RelatedMovie holds a movie related to another one, along with how similar the
+// two are, from 0 to 1.
+type RelatedMovie struct {
+	Movie *Movie  `json:"movie"`
+	Score float64 `json:"score"`
+}
+
+type RelatedMovieModelInterface interface {
+	GetForMovie(movieID int64, limit int) ([]*RelatedMovie, error)
+	Refresh(size int) (int64, error)
+}
+
+type RelatedMovieModel struct {
+	DB *sql.DB
+}
+
+// GetForMovie returns the movies most similar to the given one, best match
+// first, as of the last refresh. Movies created since then have no related
+// movies yet, and movies moved to the trash or unpublished since then are left
+// out.
+func (m RelatedMovieModel) GetForMovie(movieID int64, limit int) ([]*RelatedMovie, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.version,
+			m.rating_average, m.rating_count, m.poster, m.external_ids, r.score
+		FROM "RelatedMovies" r
+		INNER JOIN "Movies" m ON m.id = r.related_id
+		WHERE r.movie_id = $1 AND m.deleted_at IS NULL AND %s
+		ORDER BY r.score DESC, m.id ASC
+		LIMIT $2`, publishedMovieSQL("m"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related := []*RelatedMovie{}
+
+	for rows.Next() {
+		r := RelatedMovie{Movie: &Movie{}}
+		err := rows.Scan(
+			&r.Movie.ID,
+			&r.Movie.CreatedAt,
+			&r.Movie.Title,
+			&r.Movie.Year,
+			&r.Movie.Runtime,
+			pq.Array(&r.Movie.Genres),
+			&r.Movie.Version,
+			&r.Movie.RatingAverage,
+			&r.Movie.RatingCount,
+			&r.Movie.Poster,
+			&r.Movie.ExternalIDs,
+			&r.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		related = append(related, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return related, nil
+}
+
+// relatedBatchSize is the number of movies whose related movies are refreshed
+// by each query of Refresh.
+const relatedBatchSize = 100
+
+// Refresh recomputes the similarity between every pair of movies and keeps the
+// size best matches of each movie. It returns the number of pairs stored.
+//
+// Movies are refreshed in batches of consecutive ids, each scored against the
+// whole catalogue and saved on its own, so that no query holds locks for long
+// and readers keep seeing the previous matches of the movies not refreshed
+// yet. Matches are upserted, and the previous matches of the batch that didn't
+// make it again are deleted, along with those of the movies that aren't live
+// anymore.
+//
+// The score weighs the Jaccard index of the genres by 0.6, the Jaccard index of
+// the title's stemmed words by 0.3, and the proximity of the release years by
+// 0.1, where movies released 10 years apart get half the proximity of movies
+// released the same year. Only pairs sharing at least a genre or a title word
+// are scored.
+func (m RelatedMovieModel) Refresh(size int) (int64, error) {
+	var total int64
+	var after int64
+
+	for {
+		last, count, err := m.refreshBatch(size, after)
+		if err != nil {
+			return total, err
+		}
+		if last == nil {
+			return total, nil
+		}
+
+		total += count
+		after = *last
+	}
+}
+
+// refreshBatch refreshes the related movies of the next relatedBatchSize
+// movies with an id greater than after, whether they are live or not. It
+// returns the greatest id of the batch, which is nil once every movie has been
+// refreshed, and the number of pairs stored.
+func (m RelatedMovieModel) refreshBatch(size int, after int64) (*int64, int64, error) {
+	// Scoring compares every movie of the batch against the whole catalogue,
+	// which takes longer than a request-scoped query.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var last *int64
+
+	query := `
+		SELECT MAX(id)
+		FROM (SELECT id FROM "Movies" WHERE id > $1 ORDER BY id ASC LIMIT $2) AS batch`
+
+	err := m.DB.QueryRowContext(ctx, query, after, relatedBatchSize).Scan(&last)
+	if err != nil || last == nil {
+		return nil, 0, err
+	}
+
+	query = fmt.Sprintf(`
+		WITH features AS (
+			SELECT id, year, genres, TSVECTOR_TO_ARRAY(TO_TSVECTOR('english', title)) AS words
+			FROM "Movies"
+			WHERE deleted_at IS NULL AND %s
+		), scores AS (
+			SELECT
+				a.id AS movie_id,
+				b.id AS related_id,
+				0.6 * g.jaccard + 0.3 * w.jaccard + 0.1 / (1 + ABS(a.year - b.year) / 10.0) AS score
+			FROM features a
+			INNER JOIN features b ON b.id <> a.id AND (b.genres && a.genres OR b.words && a.words)
+			CROSS JOIN LATERAL (
+				SELECT COALESCE(COUNT(*) FILTER (WHERE x = ANY(a.genres) AND x = ANY(b.genres))::REAL / NULLIF(COUNT(*), 0), 0)
+				FROM (SELECT DISTINCT UNNEST(a.genres || b.genres)) AS u(x)
+			) AS g(jaccard)
+			CROSS JOIN LATERAL (
+				SELECT COALESCE(COUNT(*) FILTER (WHERE x = ANY(a.words) AND x = ANY(b.words))::REAL / NULLIF(COUNT(*), 0), 0)
+				FROM (SELECT DISTINCT UNNEST(a.words || b.words)) AS u(x)
+			) AS w(jaccard)
+			WHERE a.id > $2 AND a.id <= $3
+		), ranked AS (
+			SELECT
+				movie_id, related_id, score,
+				ROW_NUMBER() OVER (PARTITION BY movie_id ORDER BY score DESC, related_id ASC) AS rank
+			FROM scores
+		), upserted AS (
+			INSERT INTO "RelatedMovies" (movie_id, related_id, score)
+			SELECT movie_id, related_id, score
+			FROM ranked
+			WHERE rank <= $1
+			ON CONFLICT (movie_id, related_id) DO UPDATE SET score = EXCLUDED.score
+			RETURNING movie_id, related_id
+		), deleted AS (
+			DELETE FROM "RelatedMovies" r
+			WHERE
+				r.movie_id > $2 AND r.movie_id <= $3
+				AND NOT EXISTS (
+					SELECT 1 FROM upserted u
+					WHERE u.movie_id = r.movie_id AND u.related_id = r.related_id
+				)
+		)
+		SELECT COUNT(*)
+		FROM upserted`, publishedMovieSQL(`"Movies"`))
+
+	var count int64
+
+	err = m.DB.QueryRowContext(ctx, query, size, after, *last).Scan(&count)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return last, count, nil
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelatedMovieModel_GetForMovie(t *testing.T) {
+	query := `
+		SELECT
+			m.id, m.created_at, m.title, m.year, m.runtime, m.genres, m.version,
+			m.rating_average, m.rating_count, m.poster, m.external_ids, r.score
+		FROM "RelatedMovies" r
+		INNER JOIN "Movies" m ON m.id = r.related_id
+		WHERE r.movie_id = \$1 AND m.deleted_at IS NULL AND m.status = 'published' AND m.publish_at <= NOW\(\) AND \(m.unpublish_at IS NULL OR m.unpublish_at > NOW\(\)\)
+		ORDER BY r.score DESC, m.id ASC
+		LIMIT \$2`
+
+	db, mock := NewMock(t)
+	model := RelatedMovieModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "created_at", "title", "year", "runtime", "genres", "version",
+		"rating_average", "rating_count", "poster", "external_ids", "score",
+	}).
+		AddRow(2, time.Now(), "Moana 2", 2024, 100, pq.Array([]string{"animation"}), 1, "0.00", 0, nil, "{}", 0.89).
+		AddRow(3, time.Now(), "Frozen", 2013, 102, pq.Array([]string{"animation"}), 1, "0.00", 0, nil, "{}", 0.67)
+	mock.ExpectQuery(query).WithArgs(1, 10).WillReturnRows(rows)
+
+	related, err := model.GetForMovie(1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(related))
+	assert.Equal(t, "Moana 2", related[0].Movie.Title)
+	assert.Equal(t, 0.89, related[0].Score)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestRelatedMovieModel_Refresh(t *testing.T) {
+	db, mock := NewMock(t)
+	model := RelatedMovieModel{DB: db}
+	defer model.DB.Close()
+
+	batchQuery := `
+		SELECT MAX\(id\)
+		FROM \(SELECT id FROM "Movies" WHERE id > \$1 ORDER BY id ASC LIMIT \$2\) AS batch`
+	refreshQuery := `(?s)INSERT INTO "RelatedMovies" \(movie_id, related_id, score\).*` +
+		`ON CONFLICT \(movie_id, related_id\) DO UPDATE SET score = EXCLUDED.score.*` +
+		`DELETE FROM "RelatedMovies" r`
+
+	mock.ExpectQuery(batchQuery).
+		WithArgs(0, relatedBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(100))
+	mock.ExpectQuery(refreshQuery).
+		WithArgs(20, 0, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(6))
+	mock.ExpectQuery(batchQuery).
+		WithArgs(100, relatedBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(142))
+	mock.ExpectQuery(refreshQuery).
+		WithArgs(20, 100, 142).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(batchQuery).
+		WithArgs(142, relatedBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	count, err := model.Refresh(20)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9), count)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,154 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// CountryRX matches ISO 3166-1 alpha-2 country codes, such as "US" or "FR".
+var CountryRX = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// Release holds the date a movie was, or will be, released in a country, along
+// with the certification given by the country's rating board, such as "PG-13"
+// in the US or "12" in Germany. Date is formatted as "2006-01-02".
+type Release struct {
+	MovieID       int64  `json:"-"`
+	Country       string `json:"country"`
+	Date          string `json:"date"`
+	Certification string `json:"certification,omitempty"`
+}
+
+// ValidateReleases checks the full list of releases of a movie. Release dates
+// may be in the future, for movies that have only been announced.
+func ValidateReleases(v *validator.Validator, releases []*Release) {
+	v.Check(releases != nil, "releases", "must be provided")
+	v.Check(len(releases) <= 250, "releases", "must not contain more than 250 releases")
+
+	seen := make(map[string]bool)
+
+	for i, release := range releases {
+		key := fmt.Sprintf("releases[%d]", i)
+
+		v.Check(validator.Matches(release.Country, CountryRX), key+".country", "must be a valid ISO 3166-1 alpha-2 code")
+		v.Check(!seen[release.Country], key+".country", "must not duplicate another release")
+		seen[release.Country] = true
+
+		date, err := time.Parse(time.DateOnly, release.Date)
+		if err != nil {
+			v.AddError(key+".date", "must be a valid date in the format YYYY-MM-DD")
+		} else {
+			v.Check(date.Year() > 1894, key+".date", "must be later than 1894")
+		}
+
+		v.Check(len(release.Certification) <= 20, key+".certification", "must not be more than 20 bytes long")
+	}
+}
+
+type ReleaseModelInterface interface {
+	GetAllForMovie(movieID int64) ([]*Release, error)
+	GetAllForMovies(movieIDs []int64) ([]*Release, error)
+	SetForMovie(movieID int64, releases []*Release) error
+}
+
+type ReleaseModel struct {
+	DB *sql.DB
+}
+
+// GetAllForMovie returns the releases of a movie, earliest first.
+func (m ReleaseModel) GetAllForMovie(movieID int64) ([]*Release, error) {
+	return m.GetAllForMovies([]int64{movieID})
+}
+
+// GetAllForMovies returns the releases of the given movies, grouped by movie
+// and earliest first within each movie.
+func (m ReleaseModel) GetAllForMovies(movieIDs []int64) ([]*Release, error) {
+	query := `
+		SELECT movie_id, country, TO_CHAR(released_on, 'YYYY-MM-DD'), certification
+		FROM "Releases"
+		WHERE movie_id = ANY($1)
+		ORDER BY movie_id ASC, released_on ASC, country ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	releases := []*Release{}
+
+	for rows.Next() {
+		var release Release
+		err := rows.Scan(
+			&release.MovieID,
+			&release.Country,
+			&release.Date,
+			&release.Certification,
+		)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, &release)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// SetForMovie replaces all the releases of a movie in a single transaction.
+func (m ReleaseModel) SetForMovie(movieID int64, releases []*Release) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM "Releases"
+		WHERE movie_id = $1`
+
+	_, err = tx.ExecContext(ctx, query, movieID)
+	if err != nil {
+		return err
+	}
+
+	countries := make([]string, len(releases))
+	dates := make([]string, len(releases))
+	certifications := make([]string, len(releases))
+	for i, release := range releases {
+		countries[i] = release.Country
+		dates[i] = release.Date
+		certifications[i] = release.Certification
+	}
+
+	query = `
+		INSERT INTO "Releases" (movie_id, country, released_on, certification)
+		SELECT $1, release.country, release.released_on, release.certification
+		FROM UNNEST($2::TEXT[], $3::DATE[], $4::TEXT[]) AS release (country, released_on, certification)`
+	args := []any{
+		movieID,
+		pq.Array(countries),
+		pq.Array(dates),
+		pq.Array(certifications),
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
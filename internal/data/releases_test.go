@@ -0,0 +1,110 @@
+package data
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateReleases(t *testing.T) {
+	tests := []struct {
+		name     string
+		releases []*Release
+		expected map[string]string
+	}{
+		{
+			name: "Valid",
+			releases: []*Release{
+				{Country: "US", Date: "2024-03-01", Certification: "PG-13"},
+				{Country: "FR", Date: "2099-02-28"},
+			},
+			expected: map[string]string{},
+		},
+		{
+			name: "Invalid",
+			releases: []*Release{
+				{Country: "us", Date: "2024-03-01"},
+				{Country: "FR", Date: "2024-02-30"},
+				{Country: "FR", Date: "1890-01-01", Certification: "Interdit aux moins de 18 ans"},
+			},
+			expected: map[string]string{
+				"releases[0].country":       "must be a valid ISO 3166-1 alpha-2 code",
+				"releases[1].date":          "must be a valid date in the format YYYY-MM-DD",
+				"releases[2].country":       "must not duplicate another release",
+				"releases[2].date":          "must be later than 1894",
+				"releases[2].certification": "must not be more than 20 bytes long",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateReleases(v, test.releases)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+func TestReleaseModel_SetForMovie(t *testing.T) {
+	db, mock := NewMock(t)
+	model := ReleaseModel{DB: db}
+	defer model.DB.Close()
+
+	releases := []*Release{
+		{Country: "US", Date: "2024-03-01", Certification: "PG-13"},
+		{Country: "FR", Date: "2024-02-28"},
+	}
+	args := []driver.Value{
+		1,
+		pq.Array([]string{"US", "FR"}),
+		pq.Array([]string{"2024-03-01", "2024-02-28"}),
+		pq.Array([]string{"PG-13", ""}),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`
+		DELETE FROM "Releases"
+		WHERE movie_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`
+		INSERT INTO "Releases" \(movie_id, country, released_on, certification\)
+		SELECT \$1, release.country, release.released_on, release.certification
+		FROM UNNEST\(\$2::TEXT\[\], \$3::DATE\[\], \$4::TEXT\[\]\) AS release \(country, released_on, certification\)`).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	assert.Nil(t, model.SetForMovie(1, releases))
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestReleaseModel_GetAllForMovies(t *testing.T) {
+	db, mock := NewMock(t)
+	model := ReleaseModel{DB: db}
+	defer model.DB.Close()
+
+	rows := sqlmock.NewRows([]string{"movie_id", "country", "released_on", "certification"}).
+		AddRow(1, "FR", "2024-02-28", "").
+		AddRow(1, "US", "2024-03-01", "PG-13")
+	mock.ExpectQuery(`
+		SELECT movie_id, country, TO_CHAR\(released_on, 'YYYY-MM-DD'\), certification
+		FROM "Releases"
+		WHERE movie_id = ANY\(\$1\)
+		ORDER BY movie_id ASC, released_on ASC, country ASC`).
+		WithArgs(pq.Array([]int64{1})).
+		WillReturnRows(rows)
+
+	releases, err := model.GetAllForMovie(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Release{
+		{MovieID: 1, Country: "FR", Date: "2024-02-28"},
+		{MovieID: 1, Country: "US", Date: "2024-03-01", Certification: "PG-13"},
+	}, releases)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
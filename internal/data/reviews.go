@@ -0,0 +1,302 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+)
+
+// Review holds a user's rating of a movie, from 1 to 10, along with an
+// optional review text. Each user can review a movie only once.
+type Review struct {
+	MovieID   int64     `json:"movie_id"`
+	UserID    int64     `json:"user_id"`
+	UserName  string    `json:"user_name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Rating    int32     `json:"rating"`
+	Body      string    `json:"body,omitempty"`
+	Version   int32     `json:"version"`
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.Check(review.Rating != 0, "rating", "must be provided")
+	v.Check(review.Rating >= 1 && review.Rating <= 10, "rating", "must be between 1 and 10")
+
+	v.Check(len(review.Body) <= 10_000, "body", "must not be more than 10000 bytes long")
+}
+
+type ReviewModelInterface interface {
+	Create(review *Review) error
+	Get(movieID, userID int64) (*Review, error)
+	GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error)
+	Update(review *Review) error
+	Delete(movieID, userID int64) error
+}
+
+type ReviewModel struct {
+	DB *sql.DB
+}
+
+// lockMovieRating locks the movie whose reviews are about to change, so that
+// concurrent changes to its reviews are aggregated by refreshMovieRating one
+// after the other, each seeing the reviews committed by the others. It must
+// be the first statement of the transaction: inserting a review takes a KEY
+// SHARE lock on the movie, and two transactions holding it would deadlock
+// waiting for each other's lock. FOR NO KEY UPDATE doesn't conflict with KEY
+// SHARE either way. Deleting a user refreshes the ratings of the movies they
+// reviewed the same way, from a trigger.
+func lockMovieRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	_, err := tx.ExecContext(ctx, `SELECT id FROM "Movies" WHERE id = $1 FOR NO KEY UPDATE`, movieID)
+	return err
+}
+
+// refreshMovieRating recalculates the denormalized rating average and count of
+// a movie. It must run in the same transaction as the change to its reviews,
+// after lockMovieRating.
+func refreshMovieRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	query := `
+		UPDATE "Movies"
+		SET (rating_average, rating_count) = (
+			SELECT COALESCE(AVG(rating), 0), COUNT(*)
+			FROM "Reviews"
+			WHERE movie_id = $1
+		)
+		WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, movieID)
+	return err
+}
+
+func (m ReviewModel) Create(review *Review) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO "Reviews" (movie_id, user_id, rating, body)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at, version`
+	args := []any{
+		review.MovieID,
+		review.UserID,
+		review.Rating,
+		review.Body,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).
+		Scan(&review.CreatedAt, &review.UpdatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "Reviews_pkey"`:
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Get(movieID, userID int64) (*Review, error) {
+	if movieID < 1 || userID < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT r.movie_id, r.user_id, u.name, r.created_at, r.updated_at, r.rating, r.body, r.version
+		FROM "Reviews" r
+		INNER JOIN "Users" u ON u.id = r.user_id
+		WHERE r.movie_id = $1 AND r.user_id = $2`
+
+	var review Review
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, movieID, userID).Scan(
+		&review.MovieID,
+		&review.UserID,
+		&review.UserName,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Rating,
+		&review.Body,
+		&review.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+// GetAllForMovie returns a page of the reviews of a movie.
+func (m ReviewModel) GetAllForMovie(movieID int64, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(), r.movie_id, r.user_id, u.name, r.created_at, r.updated_at,
+			r.rating, r.body, r.version
+		FROM "Reviews" r
+		INNER JOIN "Users" u ON u.id = r.user_id
+		WHERE r.movie_id = $1
+		ORDER BY %s, r.user_id ASC
+		LIMIT $2 OFFSET $3`, filters.orderBy("r."))
+	args := []any{movieID, filters.limit(), filters.offset()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecord := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+		err := rows.Scan(
+			&totalRecord,
+			&review.MovieID,
+			&review.UserID,
+			&review.UserName,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+			&review.Rating,
+			&review.Body,
+			&review.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecord, filters.Page, filters.PageSize)
+	return reviews, metadata, nil
+}
+
+func (m ReviewModel) Update(review *Review) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE "Reviews"
+		SET rating = $1, body = $2, updated_at = NOW(), version = version + 1
+		WHERE movie_id = $3 AND user_id = $4 AND version = $5
+		RETURNING updated_at, version`
+	args := []any{
+		review.Rating,
+		review.Body,
+		review.MovieID,
+		review.UserID,
+		review.Version,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&review.UpdatedAt, &review.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m ReviewModel) Delete(movieID, userID int64) error {
+	if movieID < 1 || userID < 1 {
+		return ErrRecordNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = lockMovieRating(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM "Reviews"
+		WHERE movie_id = $1 AND user_id = $2`
+
+	result, err := tx.ExecContext(ctx, query, movieID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	err = refreshMovieRating(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
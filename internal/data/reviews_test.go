@@ -0,0 +1,133 @@
+package data
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateReview(t *testing.T) {
+	tests := []struct {
+		name     string
+		review   *Review
+		expected map[string]string
+	}{
+		{
+			name:     "Valid",
+			review:   &Review{Rating: 10, Body: "A masterpiece."},
+			expected: map[string]string{},
+		},
+		{
+			name:     "MissingRating",
+			review:   &Review{},
+			expected: map[string]string{"rating": "must be provided"},
+		},
+		{
+			name:     "RatingOutOfRange",
+			review:   &Review{Rating: 11},
+			expected: map[string]string{"rating": "must be between 1 and 10"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateReview(v, test.review)
+			assert.Equal(t, test.expected, v.Errors)
+		})
+	}
+}
+
+const ratingLockQuery = `SELECT id FROM "Movies" WHERE id = \$1 FOR NO KEY UPDATE`
+
+func TestReviewModel_Create(t *testing.T) {
+	insertQuery := `
+		INSERT INTO "Reviews" \(movie_id, user_id, rating, body\)
+		VALUES \(\$1, \$2, \$3, \$4\)
+		RETURNING created_at, updated_at, version`
+	refreshQuery := `
+		UPDATE "Movies"
+		SET \(rating_average, rating_count\) = \(
+			SELECT COALESCE\(AVG\(rating\), 0\), COUNT\(\*\)
+			FROM "Reviews"
+			WHERE movie_id = \$1
+		\)
+		WHERE id = \$1`
+	createdAt := time.Now()
+
+	tests := []struct {
+		name       string
+		buildMock  func(mock sqlmock.Sqlmock)
+		checkModel func(model ReviewModel)
+	}{
+		{
+			name: "Success",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"created_at", "updated_at", "version"}).
+					AddRow(createdAt, createdAt, 1)
+				mock.ExpectBegin()
+				// The movie must be locked before the insert takes a KEY SHARE
+				// lock on it, or concurrent reviews would deadlock.
+				mock.ExpectExec(ratingLockQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(insertQuery).WithArgs(1, 7, 8, "").WillReturnRows(rows)
+				mock.ExpectExec(refreshQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			checkModel: func(model ReviewModel) {
+				review := &Review{MovieID: 1, UserID: 7, Rating: 8}
+				err := model.Create(review)
+				assert.Nil(t, err)
+				assert.Equal(t, int32(1), review.Version)
+			},
+		},
+		{
+			name: "ErrDuplicateReview",
+			buildMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(ratingLockQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(insertQuery).
+					WithArgs(1, 7, 8, "").
+					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "Reviews_pkey"`))
+				mock.ExpectRollback()
+			},
+			checkModel: func(model ReviewModel) {
+				err := model.Create(&Review{MovieID: 1, UserID: 7, Rating: 8})
+				assert.Equal(t, ErrDuplicateReview, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := ReviewModel{DB: db}
+			defer model.DB.Close()
+			test.buildMock(mock)
+			test.checkModel(model)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReviewModel_Delete(t *testing.T) {
+	query := `
+		DELETE FROM "Reviews"
+		WHERE movie_id = \$1 AND user_id = \$2`
+
+	db, mock := NewMock(t)
+	model := ReviewModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(ratingLockQuery).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs(1, 7).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := model.Delete(1, 7)
+	assert.Equal(t, ErrRecordNotFound, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -3,6 +3,8 @@ package data
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -11,15 +13,70 @@ import (
 // unable to parse or convert the JSON string.
 var ErrInvalidRuntimeFormat = errors.New("invalid runtime format")
 
+// RuntimeFormat is a representation of runtimes in JSON responses.
+type RuntimeFormat string
+
+const (
+	// RuntimeFormatMinutes represents runtimes as strings like "107 mins". It is
+	// the default.
+	RuntimeFormatMinutes RuntimeFormat = "minutes"
+	// RuntimeFormatInteger represents runtimes as a number of minutes.
+	RuntimeFormatInteger RuntimeFormat = "integer"
+	// RuntimeFormatISO8601 represents runtimes as ISO 8601 durations like
+	// "PT1H47M".
+	RuntimeFormatISO8601 RuntimeFormat = "iso8601"
+)
+
+// RuntimeFormats holds the names of every RuntimeFormat.
+var RuntimeFormats = []string{
+	string(RuntimeFormatMinutes),
+	string(RuntimeFormatInteger),
+	string(RuntimeFormatISO8601),
+}
+
+var (
+	runtimeIntegerRX = regexp.MustCompile(`^-?[0-9]+$`)
+	runtimeMinutesRX = regexp.MustCompile(`^(-?[0-9]+)\s*(?:mins?)?$`)
+	runtimeHoursRX   = regexp.MustCompile(`^(?:([0-9]+)\s*h)?\s*(?:([0-9]+)\s*m)?$`)
+	runtimeISO8601RX = regexp.MustCompile(`^PT(?:([0-9]+)H)?(?:([0-9]+)M)?$`)
+)
+
 type Runtime int32
 
 // MarshalJSON returns a string in the format "<runtime> mins". Implement a
 // MarshalJSON() method on Runtime type so that it satisfies the json.Marshaler
 // interface.
 func (r Runtime) MarshalJSON() ([]byte, error) {
-	jsonValue := fmt.Sprintf("%d mins", r)
-	quotedJSONValue := strconv.Quote(jsonValue)
-	return []byte(quotedJSONValue), nil
+	return r.FormatJSON(RuntimeFormatMinutes), nil
+}
+
+// FormatJSON returns the JSON representation of the runtime in the given
+// format. Unknown formats fall back to RuntimeFormatMinutes.
+func (r Runtime) FormatJSON(format RuntimeFormat) []byte {
+	if format == RuntimeFormatInteger {
+		return strconv.AppendInt(nil, int64(r), 10)
+	}
+	return []byte(strconv.Quote(r.FormatText(format)))
+}
+
+// FormatText returns the runtime in the given format as plain text, such as a
+// CSV column. Unknown formats fall back to RuntimeFormatMinutes.
+func (r Runtime) FormatText(format RuntimeFormat) string {
+	switch format {
+	case RuntimeFormatInteger:
+		return strconv.FormatInt(int64(r), 10)
+	case RuntimeFormatISO8601:
+		duration := "PT"
+		if hours := r / 60; hours != 0 {
+			duration += fmt.Sprintf("%dH", hours)
+		}
+		if minutes := r % 60; minutes != 0 || r == 0 {
+			duration += fmt.Sprintf("%dM", minutes)
+		}
+		return duration
+	default:
+		return fmt.Sprintf("%d mins", r)
+	}
 }
 
 // UnmarshalJSON ensures that Runtime satisfies the json.Unmarshaler interface.
@@ -27,22 +84,79 @@ func (r Runtime) MarshalJSON() ([]byte, error) {
 // type), we must use a pointer receiver for this to work correctly. Otherwise,
 // we will only be modifying a copy (which is then discarded when this method
 // returns).
+//
+// Runtimes are accepted either as a number of minutes, or as a string in any
+// of the formats understood by ParseRuntime.
 func (r *Runtime) UnmarshalJSON(jsonValue []byte) error {
-	unquotedJSONValue, err := strconv.Unquote(string(jsonValue))
+	s, err := strconv.Unquote(string(jsonValue))
 	if err != nil {
-		return ErrInvalidRuntimeFormat
+		// Values other than strings must be a plain number of minutes.
+		s = string(jsonValue)
+		if !runtimeIntegerRX.MatchString(s) {
+			return ErrInvalidRuntimeFormat
+		}
 	}
 
-	parts := strings.Split(unquotedJSONValue, " ")
-	if len(parts) != 2 || parts[1] != "mins" {
-		return ErrInvalidRuntimeFormat
-	}
-
-	num, err := strconv.ParseInt(parts[0], 10, 32)
+	runtime, err := ParseRuntime(s)
 	if err != nil {
-		return ErrInvalidRuntimeFormat
+		return err
 	}
 
-	*r = Runtime(num)
+	*r = runtime
 	return nil
 }
+
+// ParseRuntime parses a runtime from a plain-text field, such as a CSV column.
+// It accepts a bare number of minutes, "<runtime> mins", hours and minutes
+// like "1h 47m", and ISO 8601 durations like "PT107M" or "PT1H47M".
+func ParseRuntime(s string) (Runtime, error) {
+	s = strings.TrimSpace(s)
+
+	var hours, minutes string
+
+	if match := runtimeMinutesRX.FindStringSubmatch(s); match != nil {
+		minutes = match[1]
+	} else if match := runtimeHoursRX.FindStringSubmatch(strings.ToLower(s)); match != nil {
+		hours, minutes = match[1], match[2]
+	} else if match := runtimeISO8601RX.FindStringSubmatch(strings.ToUpper(s)); match != nil {
+		hours, minutes = match[1], match[2]
+	}
+
+	if hours == "" && minutes == "" {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	var total int64
+
+	for _, part := range []struct {
+		value string
+		scale int64
+	}{{hours, 60}, {minutes, 1}} {
+		if part.value == "" {
+			continue
+		}
+
+		num, err := strconv.ParseInt(part.value, 10, 32)
+		if err != nil {
+			return 0, ErrInvalidRuntimeFormat
+		}
+		total += num * part.scale
+	}
+
+	if total > math.MaxInt32 || total < math.MinInt32 {
+		return 0, ErrInvalidRuntimeFormat
+	}
+
+	return Runtime(total), nil
+}
+
+// FormattedRuntime is a Runtime that is encoded to JSON in the given format,
+// rather than as "<runtime> mins".
+type FormattedRuntime struct {
+	Runtime Runtime
+	Format  RuntimeFormat
+}
+
+func (r FormattedRuntime) MarshalJSON() ([]byte, error) {
+	return r.Runtime.FormatJSON(r.Format), nil
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntime_UnmarshalJSON(t *testing.T) {
+	for _, input := range []string{`107`, `"107 mins"`, `"1h 47m"`, `"PT107M"`, `"PT1H47M"`} {
+		var runtime Runtime
+		err := json.Unmarshal([]byte(input), &runtime)
+		assert.Nil(t, err, input)
+		assert.Equal(t, Runtime(107), runtime, input)
+	}
+
+	for _, input := range []string{`107.5`, `1e2`, `null`, `true`, `"107 hours"`} {
+		var runtime Runtime
+		err := runtime.UnmarshalJSON([]byte(input))
+		assert.ErrorIs(t, err, ErrInvalidRuntimeFormat, input)
+	}
+}
+
+func TestRuntime_FormatJSON(t *testing.T) {
+	tests := []struct {
+		runtime  Runtime
+		format   RuntimeFormat
+		expected string
+	}{
+		{runtime: 107, format: RuntimeFormatMinutes, expected: `"107 mins"`},
+		{runtime: 107, format: RuntimeFormatInteger, expected: `107`},
+		{runtime: 107, format: RuntimeFormatISO8601, expected: `"PT1H47M"`},
+		{runtime: 120, format: RuntimeFormatISO8601, expected: `"PT2H"`},
+		{runtime: 45, format: RuntimeFormatISO8601, expected: `"PT45M"`},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			assert.Equal(t, test.expected, string(test.runtime.FormatJSON(test.format)))
+		})
+	}
+}
+
+func TestMovie_MarshalJSON(t *testing.T) {
+	movie := &Movie{ID: 1, Title: "Moana", Runtime: 107, Genres: []string{"animation"}, Version: 3}
+
+	js, err := json.Marshal(movie)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":1,"title":"Moana","genres":["animation"],"version":3,"rating_average":0,"rating_count":0,"runtime":"107 mins"}`, string(js))
+
+	movie.RuntimeFormat = RuntimeFormatISO8601
+	js, err = json.Marshal(movie)
+	assert.Nil(t, err)
+	assert.Contains(t, string(js), `"runtime":"PT1H47M"`)
+
+	// Unknown runtimes are left out, whatever the format.
+	js, err = json.Marshal(&Movie{ID: 2, Title: "Moana 2", RuntimeFormat: RuntimeFormatInteger})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(js), `"runtime"`)
+}
+
+func TestMovieVersion_MarshalJSON(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	version := &MovieVersion{MovieID: 2, Version: 3, CreatedAt: now, Runtime: 107, RuntimeFormat: RuntimeFormatInteger}
+
+	js, err := json.Marshal(version)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"movie_id":2,"version":3,"created_at":"2024-01-02T03:04:05Z","editor_id":null,"title":"","runtime":107}`, string(js))
+
+	changes := DiffMovieVersions(
+		&MovieVersion{Runtime: 100, RuntimeFormat: RuntimeFormatISO8601},
+		&MovieVersion{Runtime: 107, RuntimeFormat: RuntimeFormatISO8601},
+	)
+	js, err = json.Marshal(changes)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"runtime":{"from":"PT1H40M","to":"PT1H47M"}}`, string(js))
+}
@@ -0,0 +1,197 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// runtimeBucketSize is the width, in minutes, of the buckets that the runtime
+// distribution is counted in.
+const runtimeBucketSize = 30
+
+// MovieStats holds aggregated statistics about the movies matching some
+// criteria.
+type MovieStats struct {
+	TotalMovies    int             `json:"total_movies"`
+	AverageRuntime float64         `json:"average_runtime"`
+	MedianRuntime  float64         `json:"median_runtime"`
+	Genres         []GenreStats    `json:"genres"`
+	Decades        []DecadeStats   `json:"decades"`
+	Runtimes       []RuntimeBucket `json:"runtimes"`
+	Growth         []GrowthStats   `json:"growth"`
+}
+
+// GenreStats holds the number of movies in a genre and their runtimes. A movie
+// with several genres counts towards each of them.
+type GenreStats struct {
+	Genre          string  `json:"genre"`
+	Count          int     `json:"count"`
+	AverageRuntime float64 `json:"average_runtime"`
+	MinRuntime     int32   `json:"min_runtime"`
+	MaxRuntime     int32   `json:"max_runtime"`
+}
+
+// DecadeStats holds the number of movies released in a decade, such as 1990
+// for the years 1990 to 1999.
+type DecadeStats struct {
+	Decade         int32   `json:"decade"`
+	Count          int     `json:"count"`
+	AverageRuntime float64 `json:"average_runtime"`
+}
+
+// RuntimeBucket holds the number of movies whose runtime in minutes is at
+// least Min and less than Max.
+type RuntimeBucket struct {
+	Min   int32 `json:"min"`
+	Max   int32 `json:"max"`
+	Count int   `json:"count"`
+}
+
+// GrowthStats holds the number of movies added to the catalogue in a month,
+// and the number of movies in the catalogue at the end of it. Movies in the
+// trash are left out, so the totals may differ from the historical ones.
+type GrowthStats struct {
+	Month time.Time `json:"month"`
+	Added int       `json:"added"`
+	Total int       `json:"total"`
+}
+
+// GetStats computes the statistics of the movies matching the criteria. The
+// queries run in a single read-only transaction, so that they all see the same
+// snapshot of the catalogue.
+func (m MovieModel) GetStats(criteria MovieCriteria) (*MovieStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := criteria.args()
+	stats := MovieStats{
+		Genres:   []GenreStats{},
+		Decades:  []DecadeStats{},
+		Runtimes: []RuntimeBucket{},
+		Growth:   []GrowthStats{},
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*),
+			COALESCE(AVG(runtime), 0),
+			COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY runtime), 0)
+		FROM "Movies"
+		WHERE %s`, movieCriteriaSQL)
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&stats.TotalMovies, &stats.AverageRuntime, &stats.MedianRuntime)
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT g.genre, COUNT(*), AVG(runtime), MIN(runtime), MAX(runtime)
+		FROM "Movies", UNNEST(genres) AS g(genre)
+		WHERE %s
+		GROUP BY g.genre
+		ORDER BY COUNT(*) DESC, g.genre ASC`, movieCriteriaSQL)
+
+	err = queryStats(ctx, tx, query, args, func(rows *sql.Rows) error {
+		var genre GenreStats
+		err := rows.Scan(&genre.Genre, &genre.Count, &genre.AverageRuntime, &genre.MinRuntime, &genre.MaxRuntime)
+		if err != nil {
+			return err
+		}
+		stats.Genres = append(stats.Genres, genre)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT year / 10 * 10 AS decade, COUNT(*), AVG(runtime)
+		FROM "Movies"
+		WHERE %s
+		GROUP BY decade
+		ORDER BY decade ASC`, movieCriteriaSQL)
+
+	err = queryStats(ctx, tx, query, args, func(rows *sql.Rows) error {
+		var decade DecadeStats
+		err := rows.Scan(&decade.Decade, &decade.Count, &decade.AverageRuntime)
+		if err != nil {
+			return err
+		}
+		stats.Decades = append(stats.Decades, decade)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT runtime / %d * %d AS bucket, COUNT(*)
+		FROM "Movies"
+		WHERE %s
+		GROUP BY bucket
+		ORDER BY bucket ASC`, runtimeBucketSize, runtimeBucketSize, movieCriteriaSQL)
+
+	err = queryStats(ctx, tx, query, args, func(rows *sql.Rows) error {
+		var bucket RuntimeBucket
+		err := rows.Scan(&bucket.Min, &bucket.Count)
+		if err != nil {
+			return err
+		}
+		bucket.Max = bucket.Min + runtimeBucketSize
+		stats.Runtimes = append(stats.Runtimes, bucket)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	query = fmt.Sprintf(`
+		SELECT
+			DATE_TRUNC('month', created_at) AS month,
+			COUNT(*),
+			SUM(COUNT(*)) OVER (ORDER BY DATE_TRUNC('month', created_at))
+		FROM "Movies"
+		WHERE %s
+		GROUP BY month
+		ORDER BY month ASC`, movieCriteriaSQL)
+
+	err = queryStats(ctx, tx, query, args, func(rows *sql.Rows) error {
+		var growth GrowthStats
+		err := rows.Scan(&growth.Month, &growth.Added, &growth.Total)
+		if err != nil {
+			return err
+		}
+		stats.Growth = append(stats.Growth, growth)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stats, tx.Commit()
+}
+
+// queryStats runs a query and calls scan for each of the rows it returns.
+func queryStats(ctx context.Context, tx *sql.Tx, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
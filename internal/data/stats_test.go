@@ -0,0 +1,55 @@
+package data
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovieModel_GetStats(t *testing.T) {
+	db, mock := NewMock(t)
+	model := MovieModel{DB: db}
+	defer model.DB.Close()
+
+	args := []driver.Value{"", pq.Array([]string{"animation"}), 0, []byte("{}"), 0, pq.Array([]string{}), MovieStatusPublished, "", false}
+	month := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(AVG\(runtime\), 0\)`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "avg", "median"}).AddRow(3, "104.5", 105))
+	mock.ExpectQuery(`SELECT g.genre, COUNT\(\*\), AVG\(runtime\), MIN\(runtime\), MAX\(runtime\)`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"genre", "count", "avg", "min", "max"}).
+			AddRow("animation", 3, "104.5", 100, 107).
+			AddRow("comedy", 1, "100", 100, 100))
+	mock.ExpectQuery(`SELECT year / 10 \* 10 AS decade`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"decade", "count", "avg"}).
+			AddRow(2010, 2, "104.5").
+			AddRow(2020, 1, "100"))
+	mock.ExpectQuery(`SELECT runtime / 30 \* 30 AS bucket`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).AddRow(90, 3))
+	mock.ExpectQuery(`DATE_TRUNC\('month', created_at\) AS month`).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"month", "count", "sum"}).AddRow(month, 3, "3"))
+	mock.ExpectCommit()
+
+	stats, err := model.GetStats(MovieCriteria{Genres: []string{"animation"}, Status: MovieStatusPublished})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, stats.TotalMovies)
+	assert.Equal(t, 104.5, stats.AverageRuntime)
+	assert.Equal(t, []GenreStats{
+		{Genre: "animation", Count: 3, AverageRuntime: 104.5, MinRuntime: 100, MaxRuntime: 107},
+		{Genre: "comedy", Count: 1, AverageRuntime: 100, MinRuntime: 100, MaxRuntime: 100},
+	}, stats.Genres)
+	assert.Equal(t, int32(2010), stats.Decades[0].Decade)
+	assert.Equal(t, []RuntimeBucket{{Min: 90, Max: 120, Count: 3}}, stats.Runtimes)
+	assert.Equal(t, []GrowthStats{{Month: month, Added: 3, Total: 3}}, stats.Growth)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
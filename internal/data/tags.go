@@ -0,0 +1,166 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// TagCount holds a tag along with the number of movies it is on, as shown in
+// the tag cloud.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// ValidateTags checks tags that have already been normalized with Slugify.
+func ValidateTags(v *validator.Validator, tags []string) {
+	v.Check(len(tags) >= 1, "tags", "must contain at least 1 tag")
+	v.Check(len(tags) <= 20, "tags", "must not contain more than 20 tags")
+
+	for _, tag := range tags {
+		v.Check(tag != "", "tags", "must not contain empty values")
+		v.Check(len(tag) <= 50, "tags", "must not contain values more than 50 bytes long")
+	}
+
+	v.Check(validator.Unique(tags), "tags", "must not contain duplicate values")
+}
+
+type TagModelInterface interface {
+	GetAllForMovie(movieID int64) ([]string, error)
+	AddToMovie(movieID int64, tags []string) error
+	RemoveFromMovie(movieID int64, tag string) error
+	Cloud(limit int) ([]*TagCount, error)
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// GetAllForMovie returns the tags on a movie in alphabetical order.
+func (m TagModel) GetAllForMovie(movieID int64) ([]string, error) {
+	query := `
+		SELECT t.name
+		FROM "MovieTags" mt
+		INNER JOIN "Tags" t ON t.id = mt.tag_id
+		WHERE mt.movie_id = $1
+		ORDER BY t.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// AddToMovie puts the tags on a movie, creating the tags that don't exist yet.
+// Tags that are already on the movie are left as they are. The tags must not
+// contain duplicates.
+func (m TagModel) AddToMovie(movieID int64, tags []string) error {
+	// The no-op update makes RETURNING return the existing tags on conflict.
+	query := `
+		WITH tags AS (
+			INSERT INTO "Tags" (name)
+			SELECT UNNEST($2::TEXT[])
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		)
+		INSERT INTO "MovieTags" (movie_id, tag_id)
+		SELECT $1, id
+		FROM tags
+		ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, movieID, pq.Array(tags))
+	return err
+}
+
+// RemoveFromMovie takes a tag off a movie. The tag itself is kept, even if no
+// movie has it anymore.
+func (m TagModel) RemoveFromMovie(movieID int64, tag string) error {
+	query := `
+		DELETE FROM "MovieTags"
+		WHERE movie_id = $1 AND tag_id = (SELECT id FROM "Tags" WHERE name = $2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, tag)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Cloud returns the most used tags along with the number of movies they are
+// on, most used first. Movies in the trash or unpublished aren't counted, and
+// tags that are on no such movie are left out.
+func (m TagModel) Cloud(limit int) ([]*TagCount, error) {
+	query := fmt.Sprintf(`
+		SELECT t.name, COUNT(*)
+		FROM "Tags" t
+		INNER JOIN "MovieTags" mt ON mt.tag_id = t.id
+		INNER JOIN "Movies" m ON m.id = mt.movie_id
+		WHERE m.deleted_at IS NULL AND %s
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name ASC
+		LIMIT $1`, publishedMovieSQL("m"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cloud := []*TagCount{}
+
+	for rows.Next() {
+		var tag TagCount
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		cloud = append(cloud, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return cloud, nil
+}
@@ -0,0 +1,114 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		tags   []string
+		errors map[string]string
+	}{
+		{
+			name:   "Valid",
+			tags:   []string{"holiday", "oscar-winner"},
+			errors: map[string]string{},
+		},
+		{
+			name:   "Empty",
+			tags:   []string{},
+			errors: map[string]string{"tags": "must contain at least 1 tag"},
+		},
+		{
+			name:   "EmptyValue",
+			tags:   []string{""},
+			errors: map[string]string{"tags": "must not contain empty values"},
+		},
+		{
+			name:   "Duplicates",
+			tags:   []string{"holiday", "holiday"},
+			errors: map[string]string{"tags": "must not contain duplicate values"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateTags(v, test.tags)
+			assert.Equal(t, test.errors, v.Errors)
+		})
+	}
+}
+
+func TestTagModel_AddToMovie(t *testing.T) {
+	db, mock := NewMock(t)
+	model := TagModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectExec(`
+		WITH tags AS \(
+			INSERT INTO "Tags" \(name\)
+			SELECT UNNEST\(\$2::TEXT\[\]\)
+			ON CONFLICT \(name\) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		\)
+		INSERT INTO "MovieTags" \(movie_id, tag_id\)
+		SELECT \$1, id
+		FROM tags
+		ON CONFLICT DO NOTHING`).
+		WithArgs(1, pq.Array([]string{"holiday", "oscar-winner"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := model.AddToMovie(1, []string{"holiday", "oscar-winner"})
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestTagModel_RemoveFromMovie(t *testing.T) {
+	query := `
+		DELETE FROM "MovieTags"
+		WHERE movie_id = \$1 AND tag_id = \(SELECT id FROM "Tags" WHERE name = \$2\)`
+
+	t.Run("Success", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := TagModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectExec(query).WithArgs(1, "holiday").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.Nil(t, model.RemoveFromMovie(1, "holiday"))
+	})
+
+	t.Run("ErrRecordNotFound", func(t *testing.T) {
+		db, mock := NewMock(t)
+		model := TagModel{DB: db}
+		defer model.DB.Close()
+
+		mock.ExpectExec(query).WithArgs(1, "holiday").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.Equal(t, ErrRecordNotFound, model.RemoveFromMovie(1, "holiday"))
+	})
+}
+
+func TestTagModel_Cloud(t *testing.T) {
+	db, mock := NewMock(t)
+	model := TagModel{DB: db}
+	defer model.DB.Close()
+
+	mock.ExpectQuery(`SELECT t.name, COUNT\(\*\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).
+			AddRow("holiday", 4).
+			AddRow("oscar-winner", 2))
+
+	cloud, err := model.Cloud(10)
+	assert.Nil(t, err)
+	assert.Equal(t, []*TagCount{{Name: "holiday", Count: 4}, {Name: "oscar-winner", Count: 2}}, cloud)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
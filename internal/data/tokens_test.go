@@ -51,7 +51,7 @@ func TestValidateTokenPlaintext(t *testing.T) {
 
 func TestTokenModel_Create(t *testing.T) {
 	query := `
-		INSERT INTO Tokens \(hash, user_id, expiry, scope\)
+		INSERT INTO "Tokens" \(hash, user_id, expiry, scope\)
 		VALUES \(\$1, \$2, \$3, \$4\)`
 	token := &Token{
 		Hash:   []byte{1, 2},
@@ -104,7 +104,7 @@ func TestTokenModel_Create(t *testing.T) {
 
 func TestTokenModel_DeleteAllForUser(t *testing.T) {
 	query := `
-		DELETE FROM Tokens
+		DELETE FROM "Tokens"
 		WHERE scope = \$1 AND user_id = \$2`
 	userID := int64(1)
 
@@ -0,0 +1,280 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/walkccc/greenlight/internal/validator"
+)
+
+// LanguageRX matches canonical language tags, made of a primary language
+// subtag and an optional script, region or UN M.49 area subtag, such as "fr",
+// "pt-BR", "zh-Hant" or "es-419".
+var LanguageRX = regexp.MustCompile(`^[a-z]{2,3}(-([A-Z][a-z]{3}|[A-Z]{2}|[0-9]{3}))?$`)
+
+// searchConfigs maps primary language subtags to the PostgreSQL text search
+// configuration that stems words in that language. Other languages use the
+// "simple" configuration, which only lowercases words.
+var searchConfigs = map[string]string{
+	"ar": "arabic",
+	"da": "danish",
+	"de": "german",
+	"el": "greek",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"ga": "irish",
+	"hu": "hungarian",
+	"id": "indonesian",
+	"it": "italian",
+	"lt": "lithuanian",
+	"nb": "norwegian",
+	"ne": "nepali",
+	"nl": "dutch",
+	"nn": "norwegian",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sr": "serbian",
+	"sv": "swedish",
+	"ta": "tamil",
+	"tr": "turkish",
+}
+
+// CanonicalLanguage returns the canonical form of a language tag: the primary
+// subtag in lowercase, a script subtag in title case and a region subtag in
+// uppercase, joined by hyphens. For example, "PT_br" becomes "pt-BR".
+func CanonicalLanguage(tag string) string {
+	subtags := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+
+	subtags[0] = strings.ToLower(subtags[0])
+	for i := 1; i < len(subtags); i++ {
+		switch len(subtags[i]) {
+		case 2:
+			subtags[i] = strings.ToUpper(subtags[i])
+		case 4:
+			subtags[i] = strings.ToUpper(subtags[i][:1]) + strings.ToLower(subtags[i][1:])
+		}
+	}
+
+	return strings.Join(subtags, "-")
+}
+
+// primaryLanguage returns the primary subtag of a canonical language tag.
+func primaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(tag, "-")
+	return primary
+}
+
+// MovieTranslation holds the title and synopsis of a movie in one language.
+type MovieTranslation struct {
+	MovieID  int64  `json:"-"`
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Synopsis string `json:"synopsis,omitempty"`
+	Version  int32  `json:"version"`
+}
+
+func ValidateMovieTranslation(v *validator.Validator, translation *MovieTranslation) {
+	v.Check(validator.Matches(translation.Language, LanguageRX), "language", "must be a valid language tag")
+
+	v.Check(translation.Title != "", "title", "must be provided")
+	v.Check(len(translation.Title) <= 500, "title", "must not be more than 500 bytes long")
+
+	v.Check(len(translation.Synopsis) <= 10_000, "synopsis", "must not be more than 10000 bytes long")
+}
+
+// PickTranslation returns the translation in the first of the given languages
+// that one is available in, or nil if there is none. A language without a
+// region also matches translations for any of its regions, so that "pt" finds
+// "pt-BR", although an exact match is always preferred.
+func PickTranslation(translations []*MovieTranslation, languages []string) *MovieTranslation {
+	for _, language := range languages {
+		var partial *MovieTranslation
+
+		for _, translation := range translations {
+			switch {
+			case translation.Language == language:
+				return translation
+			case partial == nil && !strings.Contains(language, "-") &&
+				primaryLanguage(translation.Language) == language:
+				partial = translation
+			}
+		}
+
+		if partial != nil {
+			return partial
+		}
+	}
+
+	return nil
+}
+
+// Localize replaces the movie's title with the translated one and fills in the
+// translated synopsis. The original title is kept in OriginalTitle.
+func (movie *Movie) Localize(translation *MovieTranslation) {
+	movie.OriginalTitle = movie.Title
+	movie.Title = translation.Title
+	movie.Synopsis = translation.Synopsis
+	movie.Language = translation.Language
+}
+
+type MovieTranslationModelInterface interface {
+	GetAllForMovie(movieID int64) ([]*MovieTranslation, error)
+	GetForMovies(movieIDs []int64, languages []string) ([]*MovieTranslation, error)
+	GetAllForMovies(movieIDs []int64) ([]*MovieTranslation, error)
+	Set(translation *MovieTranslation) error
+	Delete(movieID int64, language string) error
+}
+
+type MovieTranslationModel struct {
+	DB *sql.DB
+}
+
+func (m MovieTranslationModel) GetAllForMovie(movieID int64) ([]*MovieTranslation, error) {
+	query := `
+		SELECT movie_id, language, title, synopsis, version
+		FROM "MovieTranslations"
+		WHERE movie_id = $1
+		ORDER BY language`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, movieID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMovieTranslations(rows)
+}
+
+// GetForMovies returns the translations of the given movies into any of the
+// given languages, or into a regional variant of them.
+func (m MovieTranslationModel) GetForMovies(movieIDs []int64, languages []string) ([]*MovieTranslation, error) {
+	query := `
+		SELECT movie_id, language, title, synopsis, version
+		FROM "MovieTranslations"
+		WHERE movie_id = ANY($1) AND (language = ANY($2) OR SPLIT_PART(language, '-', 1) = ANY($2))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs), pq.Array(languages))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMovieTranslations(rows)
+}
+
+// GetAllForMovies returns every translation of the given movies.
+func (m MovieTranslationModel) GetAllForMovies(movieIDs []int64) ([]*MovieTranslation, error) {
+	query := `
+		SELECT movie_id, language, title, synopsis, version
+		FROM "MovieTranslations"
+		WHERE movie_id = ANY($1)
+		ORDER BY movie_id, language`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(movieIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMovieTranslations(rows)
+}
+
+func scanMovieTranslations(rows *sql.Rows) ([]*MovieTranslation, error) {
+	translations := []*MovieTranslation{}
+
+	for rows.Next() {
+		var translation MovieTranslation
+		err := rows.Scan(
+			&translation.MovieID,
+			&translation.Language,
+			&translation.Title,
+			&translation.Synopsis,
+			&translation.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		translations = append(translations, &translation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+// Set creates or replaces the translation of a movie into the translation's
+// language, indexing it for search with the language's text search
+// configuration.
+func (m MovieTranslationModel) Set(translation *MovieTranslation) error {
+	query := `
+		INSERT INTO "MovieTranslations" (movie_id, language, title, synopsis, search_config)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (movie_id, language) DO UPDATE
+		SET
+			title = EXCLUDED.title,
+			synopsis = EXCLUDED.synopsis,
+			search_config = EXCLUDED.search_config,
+			version = "MovieTranslations".version + 1
+		RETURNING version`
+
+	searchConfig, ok := searchConfigs[primaryLanguage(translation.Language)]
+	if !ok {
+		searchConfig = "simple"
+	}
+
+	args := []any{
+		translation.MovieID,
+		translation.Language,
+		translation.Title,
+		translation.Synopsis,
+		searchConfig,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&translation.Version)
+}
+
+func (m MovieTranslationModel) Delete(movieID int64, language string) error {
+	query := `
+		DELETE FROM "MovieTranslations"
+		WHERE movie_id = $1 AND language = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, movieID, language)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalLanguage(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{tag: "FR", expected: "fr"},
+		{tag: "pt_br", expected: "pt-BR"},
+		{tag: " zh-HANT ", expected: "zh-Hant"},
+		{tag: "es-419", expected: "es-419"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.tag, func(t *testing.T) {
+			language := CanonicalLanguage(test.tag)
+			assert.Equal(t, test.expected, language)
+			assert.Regexp(t, LanguageRX, language)
+		})
+	}
+}
+
+func TestPickTranslation(t *testing.T) {
+	fr := &MovieTranslation{Language: "fr", Title: "Le Parrain"}
+	frCA := &MovieTranslation{Language: "fr-CA", Title: "Le Parrain (Québec)"}
+	ptBR := &MovieTranslation{Language: "pt-BR", Title: "O Poderoso Chefão"}
+	translations := []*MovieTranslation{fr, frCA, ptBR}
+
+	tests := []struct {
+		name      string
+		languages []string
+		expected  *MovieTranslation
+	}{
+		{name: "ExactRegion", languages: []string{"fr-CA", "fr"}, expected: frCA},
+		{name: "ExactPrimary", languages: []string{"fr"}, expected: fr},
+		{name: "FallbackToPrimary", languages: []string{"fr-BE", "fr"}, expected: fr},
+		{name: "AnyRegion", languages: []string{"pt"}, expected: ptBR},
+		{name: "Preference", languages: []string{"de", "pt-BR", "fr"}, expected: ptBR},
+		{name: "NoMatch", languages: []string{"de"}, expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, PickTranslation(translations, test.languages))
+		})
+	}
+}
+
+func TestMovie_Localize(t *testing.T) {
+	movie := &Movie{Title: "The Godfather"}
+	movie.Localize(&MovieTranslation{Language: "fr", Title: "Le Parrain", Synopsis: "Une famille."})
+
+	assert.Equal(t, "Le Parrain", movie.Title)
+	assert.Equal(t, "The Godfather", movie.OriginalTitle)
+	assert.Equal(t, "Une famille.", movie.Synopsis)
+	assert.Equal(t, "fr", movie.Language)
+}
+
+func TestMovieTranslationModel_Set(t *testing.T) {
+	query := `
+		INSERT INTO "MovieTranslations" \(movie_id, language, title, synopsis, search_config\)
+		VALUES \(\$1, \$2, \$3, \$4, \$5\)
+		ON CONFLICT \(movie_id, language\) DO UPDATE
+		SET
+			title = EXCLUDED.title,
+			synopsis = EXCLUDED.synopsis,
+			search_config = EXCLUDED.search_config,
+			version = "MovieTranslations".version \+ 1
+		RETURNING version`
+
+	tests := []struct {
+		name         string
+		language     string
+		searchConfig string
+	}{
+		{name: "Stemmed", language: "fr-CA", searchConfig: "french"},
+		{name: "Simple", language: "ja", searchConfig: "simple"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, mock := NewMock(t)
+			model := MovieTranslationModel{DB: db}
+			defer model.DB.Close()
+
+			mock.ExpectQuery(query).
+				WithArgs(1, test.language, "Title", "", test.searchConfig).
+				WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+			translation := &MovieTranslation{MovieID: 1, Language: test.language, Title: "Title"}
+			err := model.Set(translation)
+			assert.Nil(t, err)
+			assert.Equal(t, int32(2), translation.Version)
+			assert.Nil(t, mock.ExpectationsWereMet())
+		})
+	}
+}
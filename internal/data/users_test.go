@@ -145,7 +145,7 @@ func TestValidateUser(t *testing.T) {
 
 func TestUserlModel_Create(t *testing.T) {
 	query := `
-		INSERT INTO Users \(name, email, password_hash, activated\)
+		INSERT INTO "Users" \(name, email, password_hash, activated\)
 		VALUES \(\$1, \$2, \$3, \$4\)
 		RETURNING id, created_at, version`
 	passwordHash := make([]byte, 10)
@@ -186,7 +186,7 @@ func TestUserlModel_Create(t *testing.T) {
 			buildMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectQuery(query).
 					WithArgs("Jay", "jay@greenlight.com", passwordHash, activated).
-					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "users_email_key"`))
+					WillReturnError(errors.New(`pq: duplicate key value violates unique constraint "Users_email_key"`))
 			},
 			checkModel: func(model UserModel) {
 				err := model.Create(user)
@@ -228,7 +228,7 @@ func TestMUserModel_GetByEmail(t *testing.T) {
 			password_hash,
 			activated,
 			version
-		FROM Users
+		FROM "Users"
 		WHERE email = \$1`
 	createdAt := time.Now()
 
@@ -314,20 +314,20 @@ func TestMUserModel_GetForToken(t *testing.T) {
 
 	query := `
 		SELECT
-			Users.id,
-			Users.created_at,
-			Users.name,
-			Users.email,
-			Users.password_hash,
-			Users.activated,
-			Users.version
-		FROM Users
-		INNER JOIN Tokens
-			ON \(Users\.id = Tokens\.user_id\)
+			"Users".id,
+			"Users".created_at,
+			"Users".name,
+			"Users".email,
+			"Users".password_hash,
+			"Users".activated,
+			"Users".version
+		FROM "Users"
+		INNER JOIN "Tokens"
+			ON \("Users"\.id = "Tokens"\.user_id\)
 		WHERE
-			Tokens\.hash = \$1
-			AND Tokens\.scope = \$2
-			AND Tokens\.expiry > \$3`
+			"Tokens"\.hash = \$1
+			AND "Tokens"\.scope = \$2
+			AND "Tokens"\.expiry > \$3`
 	createdAt := time.Now()
 
 	tests := []struct {
@@ -0,0 +1,413 @@
+// Package jsonpatch applies RFC 7386 JSON Merge Patch and RFC 6902 JSON Patch
+// documents to JSON documents.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned if the patch document itself is malformed.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPathNotFound is returned if an operation refers to a location which
+	// doesn't exist in the document.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed is returned if a "test" operation doesn't match.
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// MergePatch applies an RFC 7386 JSON merge patch to doc: objects in the patch
+// are merged recursively, null values remove the corresponding member, and any
+// other value (including arrays) replaces the target.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p any
+
+	err := decode(doc, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	err = decode(patch, &p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any)
+	}
+
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], value)
+	}
+
+	return t
+}
+
+// Operation holds a single RFC 6902 operation. Value is kept raw so that an
+// explicit null can be told apart from a missing value.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies an RFC 6902 JSON patch to doc. The operations are applied in
+// order and if any of them fails, the whole patch fails.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+
+	err := json.Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	var root any
+
+	err = decode(doc, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		root, err = op.apply(root)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %q): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func (op Operation) apply(root any) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalidPatch)
+		}
+
+		var value any
+		err := decode(op.Value, &value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			// The whole document is replaced by the value, as with "add".
+			if len(path) == 0 {
+				return value, nil
+			}
+
+			root, err = remove(root, path)
+			if err != nil {
+				return nil, err
+			}
+			return add(root, path, value)
+		default:
+			current, err := get(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(current, value) {
+				return nil, ErrTestFailed
+			}
+			return root, nil
+		}
+
+	case "remove":
+		return remove(root, path)
+
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		// A value can't be moved into one of its own children.
+		if op.Op == "move" && len(from) < len(path) && slices.Equal(from, path[:len(from)]) {
+			return nil, fmt.Errorf("%w: cannot move %q into one of its children", ErrInvalidPatch, op.From)
+		}
+
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			root, err = remove(root, from)
+		} else {
+			value, err = deepCopy(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return add(root, path, value)
+
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+// equal reports whether two decoded JSON values are equal, as defined for the
+// "test" operation: numbers are equal if their values are, however they are
+// written, and objects are equal regardless of the order of their members.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		if !okX || !okY {
+			return a == b
+		}
+		return x.Cmp(y) == 0
+
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for key, value := range a {
+			other, ok := b[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return a == b
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference
+// tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with \"/\"", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token. The "-" token (the position after
+// the last element) is only valid when end is true.
+func arrayIndex(token string, length int, end bool) (int, error) {
+	if end && token == "-" {
+		return length, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+
+	if i > length || (!end && i == length) {
+		return 0, ErrPathNotFound
+	}
+
+	return i, nil
+}
+
+func get(node any, path []string) (any, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]any:
+			child, ok := n[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			node = child
+		case []any:
+			i, err := arrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+
+	return node, nil
+}
+
+// add returns node with value added at path. Adding to an object member
+// replaces any existing value, while adding to an array inserts the value
+// before the given index.
+func add(node any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		if len(rest) == 0 {
+			n[token] = value
+			return n, nil
+		}
+
+		child, ok := n[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+
+		child, err := add(child, rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(n), len(rest) == 0)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+
+		child, err := add(n[i], rest, value)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// remove returns node with the value at path removed. The value must exist.
+func remove(node any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidPatch)
+	}
+
+	token, rest := path[0], path[1:]
+
+	switch n := node.(type) {
+	case map[string]any:
+		child, ok := n[token]
+		if !ok {
+			return nil, ErrPathNotFound
+		}
+
+		if len(rest) == 0 {
+			delete(n, token)
+			return n, nil
+		}
+
+		child, err := remove(child, rest)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = child
+		return n, nil
+
+	case []any:
+		i, err := arrayIndex(token, len(n), false)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rest) == 0 {
+			return append(n[:i], n[i+1:]...), nil
+		}
+
+		child, err := remove(n[i], rest)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+func deepCopy(value any) (any, error) {
+	js, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var c any
+	err = decode(js, &c)
+	return c, err
+}
+
+// decode unmarshals js into dst, keeping numbers as json.Number so that they
+// survive a round trip unchanged and compare by value in "test" operations.
+func decode(js []byte, dst any) error {
+	decoder := json.NewDecoder(bytes.NewReader(js))
+	decoder.UseNumber()
+	return decoder.Decode(dst)
+}
@@ -0,0 +1,163 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const movie = `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["action","adventure"]}`
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "ReplaceField",
+			patch:    `{"title":"Black Panther: Wakanda Forever","year":2022}`,
+			expected: `{"title":"Black Panther: Wakanda Forever","year":2022,"runtime":"134 mins","genres":["action","adventure"]}`,
+		},
+		{
+			name:     "ClearField",
+			patch:    `{"runtime":null}`,
+			expected: `{"title":"Black Panther","year":2018,"genres":["action","adventure"]}`,
+		},
+		{
+			name:     "ReplaceArray",
+			patch:    `{"genres":["sci-fi"]}`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["sci-fi"]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := MergePatch([]byte(movie), []byte(test.patch))
+			assert.Nil(t, err)
+			assert.JSONEq(t, test.expected, string(patched))
+		})
+	}
+
+	t.Run("InvalidPatch", func(t *testing.T) {
+		_, err := MergePatch([]byte(movie), []byte(`{"title":`))
+		assert.ErrorIs(t, err, ErrInvalidPatch)
+	})
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+	}{
+		{
+			name:     "AppendGenre",
+			patch:    `[{"op":"add","path":"/genres/-","value":"sci-fi"}]`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["action","adventure","sci-fi"]}`,
+		},
+		{
+			name:     "InsertGenre",
+			patch:    `[{"op":"add","path":"/genres/0","value":"sci-fi"}]`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["sci-fi","action","adventure"]}`,
+		},
+		{
+			name:     "RemoveGenre",
+			patch:    `[{"op":"remove","path":"/genres/1"}]`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["action"]}`,
+		},
+		{
+			name:     "ReplaceGenre",
+			patch:    `[{"op":"replace","path":"/genres/1","value":"drama"}]`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["action","drama"]}`,
+		},
+		{
+			name: "TestThenReplace",
+			patch: `[
+				{"op":"test","path":"/year","value":2018},
+				{"op":"replace","path":"/title","value":"Wakanda"}
+			]`,
+			expected: `{"title":"Wakanda","year":2018,"runtime":"134 mins","genres":["action","adventure"]}`,
+		},
+		{
+			name:     "MoveGenre",
+			patch:    `[{"op":"move","from":"/genres/1","path":"/genres/0"}]`,
+			expected: `{"title":"Black Panther","year":2018,"runtime":"134 mins","genres":["adventure","action"]}`,
+		},
+		{
+			name:     "ReplaceRoot",
+			patch:    `[{"op":"replace","path":"","value":{"title":"Moana"}}]`,
+			expected: `{"title":"Moana"}`,
+		},
+		{
+			name:     "AddRoot",
+			patch:    `[{"op":"add","path":"","value":{"title":"Moana"}}]`,
+			expected: `{"title":"Moana"}`,
+		},
+		{
+			name: "TestNumberWrittenDifferently",
+			patch: `[
+				{"op":"test","path":"/year","value":2018.0},
+				{"op":"test","path":"","value":{"genres":["action","adventure"],"runtime":"134 mins","year":2.018e3,"title":"Black Panther"}}
+			]`,
+			expected: movie,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patched, err := Apply([]byte(movie), []byte(test.patch))
+			assert.Nil(t, err)
+			assert.JSONEq(t, test.expected, string(patched))
+		})
+	}
+
+	errorTests := []struct {
+		name     string
+		patch    string
+		expected error
+	}{
+		{
+			name:     "TestFailed",
+			patch:    `[{"op":"test","path":"/genres/0","value":"drama"}]`,
+			expected: ErrTestFailed,
+		},
+		{
+			name:     "IndexOutOfRange",
+			patch:    `[{"op":"remove","path":"/genres/2"}]`,
+			expected: ErrPathNotFound,
+		},
+		{
+			name:     "MissingMember",
+			patch:    `[{"op":"replace","path":"/director","value":"Ryan Coogler"}]`,
+			expected: ErrPathNotFound,
+		},
+		{
+			name:     "MissingValue",
+			patch:    `[{"op":"add","path":"/title"}]`,
+			expected: ErrInvalidPatch,
+		},
+		{
+			name:     "TestDifferentNumber",
+			patch:    `[{"op":"test","path":"/year","value":2018.5}]`,
+			expected: ErrTestFailed,
+		},
+		{
+			name:     "MoveIntoOwnChild",
+			patch:    `[{"op":"move","from":"/genres","path":"/genres/0"}]`,
+			expected: ErrInvalidPatch,
+		},
+		{
+			name:     "UnknownOp",
+			patch:    `[{"op":"increment","path":"/year"}]`,
+			expected: ErrInvalidPatch,
+		},
+	}
+
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Apply([]byte(movie), []byte(test.patch))
+			assert.ErrorIs(t, err, test.expected)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Package storage stores binary objects, such as uploaded images, outside of
+// the database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrInvalidKey is returned for keys that are empty, absolute, or that step
+// outside of the store with "..".
+var ErrInvalidKey = errors.New("invalid key")
+
+// BlobStore stores objects under slash-separated keys, such as
+// "posters/42/original.png", and serves them at public URLs.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing
+	// object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete removes the object stored under key. Deleting a key that doesn't
+	// exist is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object stored under key.
+	URL(key string) string
+}
+
+// validKey reports whether key is safe to use as a path below the root of a
+// store.
+func validKey(key string) bool {
+	if key == "" || key[0] == '/' {
+		return false
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{key: "posters/42/original.png", expected: true},
+		{key: "", expected: false},
+		{key: "/etc/passwd", expected: false},
+		{key: "posters/../../etc/passwd", expected: false},
+		{key: "posters//original.png", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			assert.Equal(t, test.expected, validKey(test.key))
+		})
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStore(dir, "http://localhost:4000/uploads/")
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	key := "posters/42/original.png"
+
+	err = store.Put(ctx, key, strings.NewReader("image"), 5, "image/png")
+	assert.Nil(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "posters", "42", "original.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "image", string(content))
+	assert.Equal(t, "http://localhost:4000/uploads/posters/42/original.png", store.URL(key))
+
+	err = store.Put(ctx, "posters/42/short.png", strings.NewReader("ima"), 5, "image/png")
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	err = store.Put(ctx, "../escape.png", strings.NewReader("image"), 5, "image/png")
+	assert.Equal(t, ErrInvalidKey, err)
+
+	assert.Nil(t, store.Delete(ctx, key))
+	assert.Nil(t, store.Delete(ctx, key))
+	_, err = os.Stat(filepath.Join(dir, "posters", "42", "original.png"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestS3Store(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+
+		switch r.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := NewS3Store(S3Config{
+		Endpoint:  server.URL,
+		Bucket:    "greenlight",
+		AccessKey: "minio",
+		SecretKey: "minio123",
+	})
+
+	ctx := context.Background()
+	key := "posters/42/original.png"
+
+	err := store.Put(ctx, key, strings.NewReader("image"), 5, "image/png")
+	assert.Nil(t, err)
+	err = store.Delete(ctx, key)
+	assert.Nil(t, err)
+
+	assert.Len(t, requests, 2)
+	assert.Equal(t, http.MethodPut, requests[0].Method)
+	assert.Equal(t, "/greenlight/posters/42/original.png", requests[0].URL.Path)
+	assert.Equal(t, "image", bodies[0])
+	assert.Equal(t, "image/png", requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, unsignedPayload, requests[0].Header.Get("X-Amz-Content-Sha256"))
+	assert.Regexp(
+		t,
+		`^AWS4-HMAC-SHA256 Credential=minio/\d{8}/us-east-1/s3/aws4_request, `+
+			`SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`,
+		requests[0].Header.Get("Authorization"),
+	)
+	assert.Equal(t, http.MethodDelete, requests[1].Method)
+
+	assert.Equal(t, server.URL+"/greenlight/posters/42/original.png", store.URL(key))
+}
+
+func TestS3Store_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>AccessDenied</Code></Error>")
+	}))
+	defer server.Close()
+
+	store := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "greenlight"})
+
+	err := store.Put(context.Background(), "posters/42/original.png", strings.NewReader("image"), 5, "image/png")
+	assert.ErrorContains(t, err, "AccessDenied")
+}
@@ -0,0 +1,4 @@
+DELETE FROM "Permissions"
+WHERE code = 'genres:write';
+
+DROP TABLE IF EXISTS "Genres";
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS "Genres" (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  slug TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  aliases TEXT[] NOT NULL DEFAULT '{}',
+  version INTEGER NOT NULL DEFAULT 1
+);
+
+-- Normalize the free-text genres of the existing movies to slugs. This must
+-- stay in sync with data.Slugify().
+UPDATE "Movies"
+SET genres = ARRAY(
+    SELECT DISTINCT TRIM(
+        BOTH '-'
+        FROM REGEXP_REPLACE(LOWER(TRIM(genre)), '[^a-z0-9]+', '-', 'g')
+      )
+    FROM UNNEST(genres) AS genre
+  );
+
+-- Seed the catalogue with every genre that is already in use.
+INSERT INTO "Genres" (slug, name)
+SELECT DISTINCT genre, INITCAP(REPLACE(genre, '-', ' '))
+FROM "Movies", UNNEST(genres) AS genre
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO "Permissions" (code)
+VALUES ('genres:write');
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS movies_deleted_at_index;
+
+ALTER TABLE "Movies" DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS movies_deleted_at_index ON "Movies" (deleted_at)
+WHERE deleted_at IS NOT NULL;
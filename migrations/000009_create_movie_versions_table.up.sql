@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "MovieVersions" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  editor_id BIGINT REFERENCES "Users" ON DELETE SET NULL,
+  title TEXT NOT NULL,
+  year INTEGER NOT NULL,
+  runtime INTEGER NOT NULL,
+  genres TEXT[] NOT NULL,
+  PRIMARY KEY (movie_id, version)
+);
+
+-- Start the history of the existing movies from their current version.
+INSERT INTO "MovieVersions" (movie_id, version, title, year, runtime, genres)
+SELECT id, version, title, year, runtime, genres
+FROM "Movies";
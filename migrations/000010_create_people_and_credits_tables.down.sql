@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "Credits";
+
+DROP TABLE IF EXISTS "People";
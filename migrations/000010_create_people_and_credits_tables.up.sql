@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS "People" (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  name TEXT NOT NULL,
+  birth_year INTEGER,
+  bio TEXT NOT NULL DEFAULT '',
+  version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS people_name_index ON "People" USING GIN (TO_TSVECTOR('simple', name));
+
+CREATE TABLE IF NOT EXISTS "Credits" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  person_id BIGINT NOT NULL REFERENCES "People" ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('director', 'actor', 'writer')),
+  character_name TEXT NOT NULL DEFAULT '',
+  billing_order INTEGER NOT NULL,
+  PRIMARY KEY (movie_id, billing_order),
+  UNIQUE (movie_id, person_id, role, character_name)
+);
+
+CREATE INDEX IF NOT EXISTS credits_person_id_index ON "Credits" (person_id);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "Reviews" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  user_id BIGINT NOT NULL REFERENCES "Users" ON DELETE CASCADE,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 10),
+  body TEXT NOT NULL DEFAULT '',
+  version INTEGER NOT NULL DEFAULT 1,
+  PRIMARY KEY (movie_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_user_id_index ON "Reviews" (user_id);
+
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS rating_average NUMERIC(4, 2) NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "ListEntries";
+
+DROP TABLE IF EXISTS "Lists";
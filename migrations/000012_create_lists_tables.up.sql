@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS "Lists" (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES "Users" ON DELETE CASCADE,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  name TEXT NOT NULL,
+  is_default BOOL NOT NULL DEFAULT FALSE,
+  public BOOL NOT NULL DEFAULT FALSE,
+  version INTEGER NOT NULL DEFAULT 1,
+  UNIQUE (user_id, name)
+);
+
+-- Every user has at most one default list, their watchlist.
+CREATE UNIQUE INDEX IF NOT EXISTS lists_default_index ON "Lists" (user_id)
+WHERE is_default;
+
+CREATE TABLE IF NOT EXISTS "ListEntries" (
+  list_id BIGINT NOT NULL REFERENCES "Lists" ON DELETE CASCADE,
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  position INTEGER NOT NULL CHECK (position > 0),
+  added_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  watched BOOL NOT NULL DEFAULT FALSE,
+  watched_at TIMESTAMP(0) WITH TIME ZONE,
+  PRIMARY KEY (list_id, movie_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS poster JSONB;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS "MovieTranslations" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  language TEXT NOT NULL,
+  title TEXT NOT NULL,
+  synopsis TEXT NOT NULL DEFAULT '',
+  -- The text search configuration for the language, e.g. 'french', or 'simple'
+  -- for languages that PostgreSQL has no stemmer for.
+  search_config REGCONFIG NOT NULL DEFAULT 'simple',
+  version INTEGER NOT NULL DEFAULT 1,
+  PRIMARY KEY (movie_id, language)
+);
+
+CREATE INDEX IF NOT EXISTS movie_translations_search_index ON "MovieTranslations"
+USING GIN (TO_TSVECTOR(search_config, title || ' ' || synopsis));
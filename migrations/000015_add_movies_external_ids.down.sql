@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS movies_normalized_title_index;
+
+ALTER TABLE "Movies"
+DROP COLUMN IF EXISTS external_ids;
@@ -0,0 +1,14 @@
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS external_ids JSONB NOT NULL DEFAULT '{}';
+
+-- Movies in the trash keep their identifiers, so that they can be restored
+-- without conflicts.
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_ids_imdb_index ON "Movies" ((external_ids ->> 'imdb'));
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_ids_tmdb_index ON "Movies" ((external_ids ->> 'tmdb'));
+CREATE UNIQUE INDEX IF NOT EXISTS movies_external_ids_wikidata_index ON "Movies" ((external_ids ->> 'wikidata'));
+CREATE INDEX IF NOT EXISTS movies_external_ids_index ON "Movies" USING GIN (external_ids jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS movies_normalized_title_index ON "Movies" (
+  year,
+  (REGEXP_REPLACE(LOWER(title), '[^[:alnum:]]+', '', 'g'))
+);
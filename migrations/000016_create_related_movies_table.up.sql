@@ -0,0 +1,10 @@
+-- RelatedMovies holds the precomputed similarity between movies, rebuilt
+-- periodically by the API server. Only the best matches of each movie are kept.
+CREATE TABLE IF NOT EXISTS "RelatedMovies" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  related_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  score REAL NOT NULL,
+  PRIMARY KEY (movie_id, related_id)
+);
+
+CREATE INDEX IF NOT EXISTS related_movies_score_index ON "RelatedMovies" (movie_id, score DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "CollectionEntries";
+DROP TABLE IF EXISTS "Collections";
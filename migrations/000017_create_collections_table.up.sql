@@ -0,0 +1,20 @@
+-- Collections group movies that belong together, such as the films of a
+-- trilogy or a franchise, in their intended order.
+CREATE TABLE IF NOT EXISTS "Collections" (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS collections_title_index ON "Collections" USING GIN (TO_TSVECTOR('simple', title));
+
+-- A movie belongs to at most one collection.
+CREATE TABLE IF NOT EXISTS "CollectionEntries" (
+  movie_id BIGINT PRIMARY KEY REFERENCES "Movies" ON DELETE CASCADE,
+  collection_id BIGINT NOT NULL REFERENCES "Collections" ON DELETE CASCADE,
+  position INTEGER NOT NULL CHECK (position > 0)
+);
+
+CREATE INDEX IF NOT EXISTS collection_entries_collection_id_index ON "CollectionEntries" (collection_id, position);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "MovieTags";
+DROP TABLE IF EXISTS "Tags";
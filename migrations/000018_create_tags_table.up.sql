@@ -0,0 +1,15 @@
+-- Tags are free-form labels that editors put on movies, unlike the curated
+-- genres. Names are slugs, e.g. "oscar-winner".
+CREATE TABLE IF NOT EXISTS "Tags" (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS "MovieTags" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES "Tags" ON DELETE CASCADE,
+  PRIMARY KEY (movie_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS movie_tags_tag_id_index ON "MovieTags" (tag_id);
@@ -0,0 +1,9 @@
+DELETE FROM "Permissions"
+WHERE code = 'movies:moderate';
+
+DROP INDEX IF EXISTS movies_pending_index;
+
+ALTER TABLE "Movies"
+DROP COLUMN IF EXISTS moderation_reason,
+DROP COLUMN IF EXISTS submitted_by,
+DROP COLUMN IF EXISTS status;
@@ -0,0 +1,14 @@
+-- Movies submitted by users who can't publish them directly wait in the
+-- moderation queue as pending until a moderator approves or rejects them.
+-- Existing movies are published.
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'
+  CHECK (status IN ('draft', 'pending', 'published', 'rejected')),
+ADD COLUMN IF NOT EXISTS submitted_by BIGINT REFERENCES "Users" ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS moderation_reason TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS movies_pending_index ON "Movies" (id)
+WHERE status = 'pending';
+
+INSERT INTO "Permissions" (code)
+VALUES ('movies:moderate');
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS movies_not_live_index;
+
+ALTER TABLE "Movies"
+DROP CONSTRAINT IF EXISTS movies_publish_window_check,
+DROP COLUMN IF EXISTS went_live_at,
+DROP COLUMN IF EXISTS unpublish_at,
+DROP COLUMN IF EXISTS publish_at;
@@ -0,0 +1,15 @@
+-- Published movies are only shown from publish_at until unpublish_at, if any.
+-- went_live_at records when the scheduler announced that a movie went live, so
+-- that every movie is announced once. Existing movies went live when they were
+-- created.
+ALTER TABLE "Movies"
+ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP(0) WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS went_live_at TIMESTAMP(0) WITH TIME ZONE,
+ADD CONSTRAINT movies_publish_window_check CHECK (unpublish_at > publish_at);
+
+UPDATE "Movies"
+SET publish_at = created_at, went_live_at = created_at;
+
+CREATE INDEX IF NOT EXISTS movies_not_live_index ON "Movies" (publish_at)
+WHERE went_live_at IS NULL;
@@ -0,0 +1,20 @@
+-- Every movie can have one release per country, identified by its ISO 3166-1
+-- alpha-2 code, along with the certification given by the country's rating
+-- board. Announced movies may be released in a future year.
+CREATE TABLE IF NOT EXISTS "Releases" (
+  movie_id BIGINT NOT NULL REFERENCES "Movies" ON DELETE CASCADE,
+  country TEXT NOT NULL CHECK (country ~ '^[A-Z]{2}$'),
+  released_on DATE NOT NULL,
+  certification TEXT NOT NULL DEFAULT '',
+  PRIMARY KEY (movie_id, country)
+);
+
+CREATE INDEX IF NOT EXISTS releases_country_released_on_index ON "Releases" (country, released_on);
+
+ALTER TABLE "Movies"
+DROP CONSTRAINT IF EXISTS movies_year_check;
+
+ALTER TABLE "Movies"
+ADD CONSTRAINT movies_year_check CHECK (
+    year BETWEEN 1895 AND DATE_PART('year', NOW()) + 10
+  );
@@ -0,0 +1,7 @@
+ALTER TABLE "Reviews"
+DROP CONSTRAINT IF EXISTS "Reviews_user_id_fkey",
+ADD CONSTRAINT "Reviews_user_id_fkey" FOREIGN KEY (user_id) REFERENCES "Users" ON DELETE CASCADE;
+
+DROP TRIGGER IF EXISTS users_delete_reviews ON "Users";
+
+DROP FUNCTION IF EXISTS delete_user_reviews();
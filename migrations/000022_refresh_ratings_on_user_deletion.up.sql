@@ -0,0 +1,53 @@
+-- Deleting a user used to cascade to their reviews without refreshing the
+-- ratings of the movies they reviewed. The reviews are now deleted by a
+-- trigger, which refreshes the ratings the same way the API does: the movies
+-- are locked before their reviews are aggregated again.
+CREATE OR REPLACE FUNCTION delete_user_reviews() RETURNS TRIGGER AS $$
+DECLARE
+  movie_ids BIGINT[];
+BEGIN
+  WITH deleted AS (
+    DELETE FROM "Reviews"
+    WHERE user_id = OLD.id
+    RETURNING movie_id
+  )
+  SELECT ARRAY_AGG(movie_id ORDER BY movie_id) INTO movie_ids
+  FROM deleted;
+
+  PERFORM id
+  FROM "Movies"
+  WHERE id = ANY(movie_ids)
+  ORDER BY id
+  FOR UPDATE;
+
+  UPDATE "Movies" m
+  SET (rating_average, rating_count) = (
+    SELECT COALESCE(AVG(r.rating), 0), COUNT(*)
+    FROM "Reviews" r
+    WHERE r.movie_id = m.id
+  )
+  WHERE m.id = ANY(movie_ids);
+
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_delete_reviews ON "Users";
+
+CREATE TRIGGER users_delete_reviews
+BEFORE DELETE ON "Users"
+FOR EACH ROW EXECUTE FUNCTION delete_user_reviews();
+
+-- Without the cascade, deleting a user whose reviews weren't removed by the
+-- trigger fails instead of leaving the ratings out of date.
+ALTER TABLE "Reviews"
+DROP CONSTRAINT IF EXISTS "Reviews_user_id_fkey",
+ADD CONSTRAINT "Reviews_user_id_fkey" FOREIGN KEY (user_id) REFERENCES "Users";
+
+-- Fix the ratings that are out of date already.
+UPDATE "Movies" m
+SET (rating_average, rating_count) = (
+  SELECT COALESCE(AVG(r.rating), 0), COUNT(*)
+  FROM "Reviews" r
+  WHERE r.movie_id = m.id
+);
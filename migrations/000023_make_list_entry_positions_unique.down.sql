@@ -0,0 +1 @@
+ALTER TABLE "ListEntries" DROP CONSTRAINT IF EXISTS list_entries_position_key;
@@ -0,0 +1,17 @@
+-- Movies purged from the trash take their list entries with them, and the
+-- positions of the remaining entries used to be counted rather than read, so
+-- entries appended afterwards could share a position. Renumber the entries of
+-- every list without gaps, keeping their order.
+UPDATE "ListEntries" e
+SET position = ranked.position
+FROM (
+  SELECT list_id, movie_id,
+    ROW_NUMBER() OVER (PARTITION BY list_id ORDER BY position, added_at, movie_id) AS position
+  FROM "ListEntries"
+) ranked
+WHERE e.list_id = ranked.list_id AND e.movie_id = ranked.movie_id AND e.position <> ranked.position;
+
+-- The constraint is deferred since shifting entries to make room for another
+-- one briefly gives two of them the same position.
+ALTER TABLE "ListEntries"
+ADD CONSTRAINT list_entries_position_key UNIQUE (list_id, position) DEFERRABLE INITIALLY DEFERRED;
@@ -0,0 +1 @@
+ALTER TABLE "CollectionEntries" DROP CONSTRAINT IF EXISTS collection_entries_position_key;
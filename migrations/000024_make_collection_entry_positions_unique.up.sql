@@ -0,0 +1,17 @@
+-- Movies purged from the trash take their collection entries with them, and
+-- the positions of the remaining entries used to be counted rather than read,
+-- so entries appended afterwards could share a position. Renumber the entries
+-- of every collection without gaps, keeping their order.
+UPDATE "CollectionEntries" e
+SET position = ranked.position
+FROM (
+  SELECT movie_id,
+    ROW_NUMBER() OVER (PARTITION BY collection_id ORDER BY position, movie_id) AS position
+  FROM "CollectionEntries"
+) ranked
+WHERE e.movie_id = ranked.movie_id AND e.position <> ranked.position;
+
+-- The constraint is deferred since shifting entries to make room for another
+-- one briefly gives two of them the same position.
+ALTER TABLE "CollectionEntries"
+ADD CONSTRAINT collection_entries_position_key UNIQUE (collection_id, position) DEFERRABLE INITIALLY DEFERRED;
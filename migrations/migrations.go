@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the database schema, so that
+// the application can set up a database on its own, such as before restoring a
+// backup into it.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS